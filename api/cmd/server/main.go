@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,11 +19,16 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	"encurtador/internal/config"
+	"encurtador/internal/geoip"
 	"encurtador/internal/handler"
 	"encurtador/internal/middleware"
+	"encurtador/internal/model"
 	"encurtador/internal/repository"
+	"encurtador/internal/reputation"
 	"encurtador/internal/service"
 	"encurtador/migrations"
+	"encurtador/pages"
+	"encurtador/webui"
 )
 
 const (
@@ -68,14 +75,116 @@ func main() {
 
 	repo := repository.NewMySQLURLRepository(db)
 	cache := repository.NewRedisURLCache(redisClient)
-	svc := service.NewURLService(repo, cache, cfg.BaseURL)
-	h := handler.NewURLHandler(svc, cfg.FrontendURL)
-
+	if cfg.PasswordCacheSecret != "" {
+		if cache, err = repository.NewSealedURLCache(cache, []byte(cfg.PasswordCacheSecret)); err != nil {
+			slog.Error("creating sealed cache", "error", err)
+			os.Exit(1)
+		}
+	}
+	attempts := repository.NewRedisAttemptTracker(redisClient)
+	clicks := repository.NewRedisClickCounter(redisClient)
+	clickLog := repository.NewMySQLClickLog(db)
+	domains := repository.NewMySQLDomainRules(db)
+	reports := repository.NewMySQLAbuseReports(db)
+	auditLog := repository.NewMySQLAuditLog(db)
+	unlockSecret, err := hmacSecret("UNLOCK_TOKEN_SECRET", cfg.UnlockTokenSecret)
+	if err != nil {
+		slog.Error("generating unlock token secret", "error", err)
+		os.Exit(1)
+	}
+	ipHashSecret, err := hmacSecret("IP_HASH_SECRET", cfg.IPHashSecret)
+	if err != nil {
+		slog.Error("generating IP hash secret", "error", err)
+		os.Exit(1)
+	}
 	appCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+
+	scfg := service.Config{
+		BaseURL:                 cfg.BaseURL,
+		TTL:                     service.TTLPolicy(cfg.TTL),
+		Lockout:                 service.LockoutPolicy(cfg.Lockout),
+		Argon2:                  service.Argon2Params(cfg.Argon2),
+		MaxPasswordChecks:       int(cfg.MaxPasswordChecks),
+		UnlockTokenSecret:       unlockSecret,
+		UnlockTokenTTL:          cfg.UnlockTokenTTL,
+		SlideInterval:           cfg.SlideInterval,
+		DomainRefresh:           cfg.DomainRefresh,
+		ShortDomains:            cfg.ShortDomains,
+		RejectShorteners:        cfg.RejectShorteners,
+		AllowPrivateTargets:     cfg.AllowPrivateTargets,
+		PrivateTargetExemptions: cfg.PrivateTargetExemptions,
+		RescanInterval:          cfg.ThreatRescan,
+		IPHashSecret:            ipHashSecret,
+	}
+	if len(cfg.ThreatLists) > 0 {
+		checker, err := reputation.Open(cfg.ThreatLists)
+		if err != nil {
+			slog.Error("loading threat lists", "error", err)
+			os.Exit(1)
+		}
+		go checker.Watch(appCtx, cfg.ThreatListReload)
+		scfg.Reputation = checker
+	}
+	svc := service.NewURLService(repo, cache, attempts, clicks, clickLog, domains, reports, auditLog, scfg)
+	if err := svc.LoadDomainRules(context.Background()); err != nil {
+		slog.Error("loading domain rules", "error", err)
+		os.Exit(1)
+	}
+	hcfg := handler.Config{
+		FrontendURL:           cfg.FrontendURL,
+		SecureCookies:         strings.HasPrefix(cfg.BaseURL, "https://"),
+		ServerGate:            cfg.GateMode == config.GateModeServer,
+		PendingURL:            cfg.PendingURL,
+		DefaultRedirectStatus: int(cfg.RedirectStatus),
+		RedirectMaxAge:        cfg.RedirectMaxAge,
+		DefaultUTM:            model.UTM(cfg.DefaultUTM),
+	}
+	if cfg.GeoIPDatabase != "" {
+		locator, err := geoip.Open(cfg.GeoIPDatabase)
+		if err != nil {
+			slog.Error("loading geoip database", "error", err)
+			os.Exit(1)
+		}
+		go locator.Watch(appCtx, cfg.GeoIPReload)
+		go reloadOnHangup(appCtx, locator)
+		hcfg.GeoIP = locator
+	}
+	h := handler.NewURLHandler(svc, hcfg)
+	admin := handler.NewAdminHandler(svc)
+
 	go svc.RunCleanup(appCtx)
+	go svc.RunDomainRefresh(appCtx)
+	go svc.RunReputationScan(appCtx)
+
+	// The click log outlives appCtx so clicks from requests still in flight
+	// during shutdown are written before the process exits.
+	logCtx, stopLog := context.WithCancel(context.Background())
+	logDone := make(chan struct{})
+	go func() {
+		defer close(logDone)
+		svc.RunClickLog(logCtx)
+	}()
+
+	limits, err := newRateLimiters(cfg.RateLimits)
+	if err != nil {
+		slog.Error("configuring rate limits", "error", err)
+		os.Exit(1)
+	}
 
-	r := buildRouter(h, cfg.CORSAllowedOrigin, cfg.FrontendURL)
+	var spa *handler.SPAHandler
+	if cfg.ServeFrontend {
+		if spa, err = newSPAHandler(); err != nil {
+			slog.Error("loading embedded frontend", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	r, err := buildRouter(h, admin, spa, limits, cfg)
+	if err != nil {
+		slog.Error("building router", "error", err)
+		os.Exit(1)
+	}
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.AppPort,
@@ -106,6 +215,8 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		slog.Error("graceful shutdown failed", "error", err)
 	}
+	stopLog()
+	<-logDone
 }
 
 func newJSONLogger() *slog.Logger {
@@ -121,42 +232,167 @@ func newJSONLogger() *slog.Logger {
 	return slog.New(handler).With("service", serviceName)
 }
 
-func buildRouter(h *handler.URLHandler, corsOrigin, frontendURL string) *gin.Engine {
+// rateLimiters holds one independent per-IP budget per route group.
+type rateLimiters struct {
+	create   gin.HandlerFunc
+	check    gin.HandlerFunc
+	unlock   gin.HandlerFunc
+	redirect gin.HandlerFunc
+	expire   gin.HandlerFunc
+	manage   gin.HandlerFunc
+	report   gin.HandlerFunc
+	admin    gin.HandlerFunc
+}
+
+func newRateLimiters(cfg config.RateLimitConfig) (*rateLimiters, error) {
+	allow, err := middleware.NewAllowlist(cfg.Allowlist)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := &rateLimiters{}
+	policies := []struct {
+		name string
+		rate string
+		dst  *gin.HandlerFunc
+	}{
+		{"create", cfg.Create, &limits.create},
+		{"check", cfg.Check, &limits.check},
+		{"unlock", cfg.Unlock, &limits.unlock},
+		{"redirect", cfg.Redirect, &limits.redirect},
+		{"expire", cfg.Expire, &limits.expire},
+		{"manage", cfg.Manage, &limits.manage},
+		{"report", cfg.Report, &limits.report},
+		{"admin", cfg.Admin, &limits.admin},
+	}
+	for _, p := range policies {
+		mw, err := middleware.NewRateLimiter(p.rate, allow)
+		if err != nil {
+			return nil, fmt.Errorf("%s rate limit: %w", p.name, err)
+		}
+		*p.dst = mw
+	}
+	return limits, nil
+}
+
+func newSPAHandler() (*handler.SPAHandler, error) {
+	files, ok := webui.FS()
+	if !ok {
+		return nil, fmt.Errorf("SERVE_FRONTEND is set but the binary was built without the embedweb tag and web/dist")
+	}
+	return handler.NewSPAHandler(files)
+}
+
+// buildRouter wires every route. spa is nil unless the embedded frontend is
+// served, in which case CORS is only installed if an extra origin is set.
+func buildRouter(h *handler.URLHandler, admin *handler.AdminHandler, spa *handler.SPAHandler, limits *rateLimiters, cfg *config.Config) (*gin.Engine, error) {
 	r := gin.New()
 	r.Use(gin.Logger(), gin.Recovery())
 	r.SetTrustedProxies([]string{defaultTrustedProxy})
+	r.SetHTMLTemplate(pages.Templates())
 
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{corsOrigin},
-		AllowMethods:     []string{"GET", "POST"},
-		AllowHeaders:     []string{"Content-Type"},
-		AllowCredentials: false,
-		MaxAge:           12 * time.Hour,
-	}))
-
-	// A single rate limiter instance is shared across the redirect and unlock
-	// routes so that enumeration attempts and password guesses count toward
-	// the same per-IP budget.
-	rl := middleware.NewRateLimiter()
-
-	r.GET("/", func(c *gin.Context) {
-		c.Redirect(http.StatusFound, frontendURL)
-	})
+	if cfg.CORSAllowedOrigin != "" {
+		// Credentials are allowed so the gate's unlock call can set the
+		// remember-unlock cookie on the API origin.
+		r.Use(cors.New(cors.Config{
+			AllowOrigins:     []string{cfg.CORSAllowedOrigin},
+			AllowMethods:     []string{"GET", "POST", "PATCH"},
+			AllowHeaders:     []string{"Content-Type"},
+			ExposeHeaders:    []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+			AllowCredentials: true,
+			MaxAge:           12 * time.Hour,
+		}))
+	}
+
+	if spa != nil {
+		if err := registerFrontend(r, spa); err != nil {
+			return nil, err
+		}
+	} else if cfg.FrontendURL != "" {
+		r.GET("/", func(c *gin.Context) {
+			c.Redirect(http.StatusFound, cfg.FrontendURL)
+		})
+	}
 
 	api := r.Group(apiV1BasePath)
 	{
-		api.POST("/urls", h.CreateURL)
-		api.GET("/urls/check/:slug", h.CheckSlug)
-		api.POST("/urls/:slug/unlock", rl, h.UnlockURL)
-		api.POST("/urls/:slug/expire", h.ExpireURL)
+		api.POST("/urls", limits.create, h.CreateURL)
+		api.GET("/urls/check/:slug", limits.check, h.CheckSlug)
+		api.POST("/urls/:slug/unlock", limits.unlock, h.UnlockURL)
+		api.POST("/urls/:slug/expire", limits.expire, h.ExpireURL)
+		api.POST("/urls/:slug/info", limits.manage, h.LinkInfo)
+		api.PATCH("/urls/:slug", limits.manage, h.UpdateURL)
+		api.POST("/urls/:slug/password", limits.manage, h.ChangePassword)
+		api.POST("/urls/:slug/rotate-token", limits.manage, h.RotateToken)
+		api.POST("/urls/:slug/audit", limits.manage, h.AuditTrail)
+		api.POST("/urls/:slug/report", limits.report, h.ReportURL)
 		api.GET("/health", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "ok"})
 		})
 	}
 
-	r.GET("/:slug", rl, h.RedirectOrGate)
+	// The admin API only exists when an admin token is configured. The
+	// limiter runs first so token guessing is throttled too.
+	if cfg.AdminToken != "" {
+		ops := r.Group(apiV1BasePath+"/admin", limits.admin, middleware.RequireAdmin(cfg.AdminToken))
+		{
+			ops.GET("/urls/:slug", admin.GetURL)
+			ops.POST("/urls/:slug/disable", admin.DisableURL)
+			ops.POST("/urls/:slug/enable", admin.EnableURL)
+			ops.POST("/urls/:slug/expire", admin.ExpireURL)
+			ops.GET("/reports", admin.ListReports)
+		}
+	}
+
+	// Prefix links append anything below the slug to their target.
+	r.GET("/:slug", limits.redirect, h.RedirectOrGate)
+	r.GET("/:slug/*rest", limits.redirect, h.RedirectOrGate)
+	if cfg.GateMode == config.GateModeServer {
+		// The server-rendered gate posts the password back to the short link
+		// itself, so guesses share the unlock budget.
+		r.POST("/:slug", limits.unlock, h.SubmitGate)
+		r.POST("/:slug/*rest", limits.unlock, h.SubmitGate)
+	}
+
+	return r, nil
+}
+
+// registerFrontend serves the embedded SPA at the site root. Only the SPA's
+// own client-side routes fall back to index.html; every other single-segment
+// path still reaches the /:slug redirect.
+func registerFrontend(r *gin.Engine, spa *handler.SPAHandler) error {
+	r.GET("/", spa.Index)
+	r.GET("/404", spa.Index)
+	r.GET("/gate/:slug", spa.Index)
+	r.GET("/manage/:slug", spa.Index)
+
+	routes, err := spa.Routes()
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		r.GET(route, spa.File)
+	}
+	return nil
+}
+
+// reloadOnHangup reloads the GeoIP database on SIGHUP, for deployments that
+// replace the file and want it picked up before the next poll.
+func reloadOnHangup(ctx context.Context, locator *geoip.Locator) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
 
-	return r
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := locator.Reload(); err != nil {
+				slog.Warn("reloading geoip database", "error", err)
+			}
+		}
+	}
 }
 
 func connectMySQL(dsn string) (*sqlx.DB, error) {
@@ -184,11 +420,54 @@ func connectRedis(addr, password string) (*redis.Client, error) {
 	return client, nil
 }
 
-// runMigrations executes an idempotent schema bootstrap SQL file.
+// hmacSecret returns the configured HMAC secret, or a random one when none is
+// set in the variable named env. A random secret works for a single instance
+// changes on restart: remember-unlock tokens stop validating, and IP hashes
+// no longer match those recorded before.
+func hmacSecret(env, configured string) ([]byte, error) {
+	if configured != "" {
+		return []byte(configured), nil
+	}
+	slog.Warn(env + " not set, using a random per-process secret")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// runMigrations applies every embedded migration that is not yet recorded in
+// schema_migrations, in order.
 func runMigrations(db *sqlx.DB) error {
-	_, err := db.Exec(migrations.BootstrapSQL)
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version    VARCHAR(100) NOT NULL PRIMARY KEY,
+		  applied_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	all, err := migrations.All()
 	if err != nil {
-		return fmt.Errorf("running migrations: %w", err)
+		return err
+	}
+	for _, m := range all {
+		var applied bool
+		if err := db.Get(&applied, `SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = ?)`, m.Version); err != nil {
+			return fmt.Errorf("checking migration %s: %w", m.Version, err)
+		}
+		if applied {
+			continue
+		}
+		for _, stmt := range m.Statements {
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("running migration %s: %w", m.Version, err)
+			}
+		}
+		if _, err := db.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, m.Version); err != nil {
+			return fmt.Errorf("recording migration %s: %w", m.Version, err)
+		}
+		slog.Info("applied migration", "version", m.Version)
 	}
 	return nil
 }
@@ -1,29 +1,143 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Gate modes select who renders the password gate for protected links.
+const (
+	GateModeFrontend = "frontend"
+	GateModeServer   = "server"
+)
+
+// minSecretLength is the shortest accepted HMAC secret, matching the SHA-256
+// block of entropy the tokens are meant to carry.
+const minSecretLength = 32
+
 type Config struct {
-	MySQLDSN          string
-	RedisAddr         string
-	RedisPassword     string
-	AppPort           string
-	BaseURL           string
-	CORSAllowedOrigin string
-	FrontendURL       string
+	MySQLDSN                string
+	RedisAddr               string
+	RedisPassword           string
+	AppPort                 string
+	BaseURL                 string
+	CORSAllowedOrigin       string
+	FrontendURL             string
+	GateMode                string
+	ServeFrontend           bool
+	PendingURL              string
+	TTL                     TTLConfig
+	RateLimits              RateLimitConfig
+	Lockout                 LockoutConfig
+	Argon2                  Argon2Config
+	MaxPasswordChecks       int64
+	UnlockTokenSecret       string
+	UnlockTokenTTL          time.Duration
+	SlideInterval           time.Duration
+	RedirectStatus          int64
+	RedirectMaxAge          time.Duration
+	DefaultUTM              UTMConfig
+	GeoIPDatabase           string
+	GeoIPReload             time.Duration
+	DomainRefresh           time.Duration
+	ShortDomains            []string
+	RejectShorteners        bool
+	AllowPrivateTargets     bool
+	PrivateTargetExemptions []string
+	ThreatLists             []string
+	ThreatListReload        time.Duration
+	ThreatRescan            time.Duration
+	AdminToken              string
+	IPHashSecret            string
+	PasswordCacheSecret     string
+}
+
+// UTMConfig holds the campaign parameters added to links without their own.
+type UTMConfig struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
+
+// TTLConfig bounds the lifetime clients may request for a link.
+type TTLConfig struct {
+	Min            time.Duration
+	Max            time.Duration
+	AllowPermanent bool
+}
+
+// LockoutConfig controls per-slug brute-force protection for protected links.
+type LockoutConfig struct {
+	Threshold   int64
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// Argon2Config sets the Argon2id costs of new link password hashes. Memory is
+// in KiB.
+type Argon2Config struct {
+	Memory      int64
+	Iterations  int64
+	Parallelism int64
+}
+
+// RateLimitConfig holds one per-IP budget per route group, each in limiter's
+// "<limit>-<period>" format (e.g. "60-M"), plus IPs/CIDRs exempt from all of
+// them. It can be loaded from a JSON file named by RATE_LIMIT_CONFIG and
+// overridden field by field through RATE_LIMIT_* environment variables.
+type RateLimitConfig struct {
+	Create    string   `json:"create"`
+	Check     string   `json:"check"`
+	Unlock    string   `json:"unlock"`
+	Redirect  string   `json:"redirect"`
+	Expire    string   `json:"expire"`
+	Manage    string   `json:"manage"`
+	Report    string   `json:"report"`
+	Admin     string   `json:"admin"`
+	Allowlist []string `json:"allowlist"`
+}
+
+var defaultRateLimits = RateLimitConfig{
+	Create:   "10-M",
+	Check:    "60-M",
+	Unlock:   "20-M",
+	Redirect: "60-M",
+	Expire:   "10-M",
+	Manage:   "30-M",
+	Report:   "10-H",
+	Admin:    "120-M",
 }
 
 func Load() (*Config, error) {
 	cfg := &Config{
-		MySQLDSN:          os.Getenv("MYSQL_DSN"),
-		RedisAddr:         os.Getenv("REDIS_ADDR"),
-		RedisPassword:     os.Getenv("REDIS_PASSWORD"),
-		AppPort:           os.Getenv("APP_PORT"),
-		BaseURL:           os.Getenv("BASE_URL"),
-		CORSAllowedOrigin: os.Getenv("CORS_ALLOWED_ORIGIN"),
-		FrontendURL:       os.Getenv("FRONTEND_URL"),
+		MySQLDSN:            os.Getenv("MYSQL_DSN"),
+		RedisAddr:           os.Getenv("REDIS_ADDR"),
+		RedisPassword:       os.Getenv("REDIS_PASSWORD"),
+		AppPort:             os.Getenv("APP_PORT"),
+		BaseURL:             os.Getenv("BASE_URL"),
+		CORSAllowedOrigin:   os.Getenv("CORS_ALLOWED_ORIGIN"),
+		FrontendURL:         os.Getenv("FRONTEND_URL"),
+		GateMode:            os.Getenv("GATE_MODE"),
+		PendingURL:          os.Getenv("PENDING_URL"),
+		UnlockTokenSecret:   os.Getenv("UNLOCK_TOKEN_SECRET"),
+		GeoIPDatabase:       os.Getenv("GEOIP_DATABASE"),
+		AdminToken:          os.Getenv("ADMIN_TOKEN"),
+		IPHashSecret:        os.Getenv("IP_HASH_SECRET"),
+		PasswordCacheSecret: os.Getenv("PASSWORD_CACHE_SECRET"),
+		DefaultUTM: UTMConfig{
+			Source:   os.Getenv("DEFAULT_UTM_SOURCE"),
+			Medium:   os.Getenv("DEFAULT_UTM_MEDIUM"),
+			Campaign: os.Getenv("DEFAULT_UTM_CAMPAIGN"),
+			Term:     os.Getenv("DEFAULT_UTM_TERM"),
+			Content:  os.Getenv("DEFAULT_UTM_CONTENT"),
+		},
 	}
 
 	if cfg.MySQLDSN == "" {
@@ -38,12 +152,239 @@ func Load() (*Config, error) {
 	if cfg.AppPort == "" {
 		cfg.AppPort = "8080"
 	}
-	if cfg.CORSAllowedOrigin == "" {
+	var err error
+	if cfg.ServeFrontend, err = boolEnv("SERVE_FRONTEND"); err != nil {
+		return nil, err
+	}
+	// An embedded frontend shares the API's origin, so it needs no CORS and
+	// its gate and 404 pages live under BASE_URL.
+	if cfg.ServeFrontend && cfg.FrontendURL == "" {
+		cfg.FrontendURL = cfg.BaseURL
+	}
+	if cfg.CORSAllowedOrigin == "" && !cfg.ServeFrontend {
 		return nil, fmt.Errorf("CORS_ALLOWED_ORIGIN is required")
 	}
-	if cfg.FrontendURL == "" {
+	switch cfg.GateMode {
+	case "":
+		cfg.GateMode = GateModeFrontend
+	case GateModeFrontend, GateModeServer:
+	default:
+		return nil, fmt.Errorf("GATE_MODE must be %q or %q", GateModeFrontend, GateModeServer)
+	}
+	// The server-rendered gate makes the frontend optional.
+	if cfg.FrontendURL == "" && cfg.GateMode == GateModeFrontend {
 		return nil, fmt.Errorf("FRONTEND_URL is required")
 	}
 
+	if cfg.TTL.Min, err = durationEnv("MIN_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.TTL.Max, err = durationEnv("MAX_TTL", 365*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.TTL.Max < cfg.TTL.Min {
+		return nil, fmt.Errorf("MAX_TTL must not be shorter than MIN_TTL")
+	}
+	if cfg.TTL.AllowPermanent, err = boolEnv("ALLOW_PERMANENT_LINKS"); err != nil {
+		return nil, err
+	}
+
+	if cfg.SlideInterval, err = durationEnv("SLIDING_EXTEND_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.RedirectStatus, err = intEnv("DEFAULT_REDIRECT_STATUS", 301); err != nil {
+		return nil, err
+	}
+	switch cfg.RedirectStatus {
+	case 301, 302, 307, 308:
+	default:
+		return nil, fmt.Errorf("DEFAULT_REDIRECT_STATUS must be 301, 302, 307 or 308")
+	}
+	if cfg.RedirectMaxAge, err = durationEnv("REDIRECT_CACHE_MAX_AGE", 24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.GeoIPReload, err = durationEnv("GEOIP_RELOAD_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.DomainRefresh, err = durationEnv("DOMAIN_RULES_REFRESH", time.Minute); err != nil {
+		return nil, err
+	}
+	cfg.ShortDomains = splitList(os.Getenv("SHORT_DOMAINS"))
+	if cfg.RejectShorteners, err = boolEnv("REJECT_SHORTENER_TARGETS"); err != nil {
+		return nil, err
+	}
+	if cfg.AllowPrivateTargets, err = boolEnv("ALLOW_PRIVATE_TARGETS"); err != nil {
+		return nil, err
+	}
+	cfg.PrivateTargetExemptions = splitList(os.Getenv("PRIVATE_TARGET_EXEMPTIONS"))
+	cfg.ThreatLists = splitList(os.Getenv("THREAT_LISTS"))
+	if cfg.ThreatListReload, err = durationEnv("THREAT_LIST_RELOAD_INTERVAL", 30*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.ThreatRescan, err = durationEnv("THREAT_RESCAN_INTERVAL", 6*time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.RateLimits, err = loadRateLimits(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Lockout.Threshold, err = intEnv("UNLOCK_LOCKOUT_THRESHOLD", 5); err != nil {
+		return nil, err
+	}
+	if cfg.Lockout.Window, err = durationEnv("UNLOCK_LOCKOUT_WINDOW", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.Lockout.BaseLockout, err = durationEnv("UNLOCK_LOCKOUT_BASE", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.Lockout.MaxLockout, err = durationEnv("UNLOCK_LOCKOUT_MAX", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.Lockout.MaxLockout < cfg.Lockout.BaseLockout {
+		return nil, fmt.Errorf("UNLOCK_LOCKOUT_MAX must not be shorter than UNLOCK_LOCKOUT_BASE")
+	}
+
+	if cfg.Argon2.Memory, err = intEnv("ARGON2_MEMORY", 19456); err != nil {
+		return nil, err
+	}
+	if cfg.Argon2.Iterations, err = intEnv("ARGON2_ITERATIONS", 2); err != nil {
+		return nil, err
+	}
+	if cfg.Argon2.Parallelism, err = intEnv("ARGON2_PARALLELISM", 1); err != nil {
+		return nil, err
+	}
+	if cfg.Argon2.Parallelism < 1 || cfg.Argon2.Parallelism > 255 {
+		return nil, fmt.Errorf("ARGON2_PARALLELISM must be between 1 and 255")
+	}
+	if cfg.Argon2.Memory < 8*cfg.Argon2.Parallelism || cfg.Argon2.Memory > 4*1024*1024 {
+		return nil, fmt.Errorf("ARGON2_MEMORY must be between 8 KiB per lane and 4 GiB, in KiB")
+	}
+	if cfg.Argon2.Iterations < 1 || cfg.Argon2.Iterations > 100 {
+		return nil, fmt.Errorf("ARGON2_ITERATIONS must be between 1 and 100")
+	}
+	if cfg.MaxPasswordChecks, err = intEnv("MAX_PASSWORD_CHECKS", 16); err != nil {
+		return nil, err
+	}
+	if cfg.MaxPasswordChecks < 1 {
+		return nil, fmt.Errorf("MAX_PASSWORD_CHECKS must be at least 1")
+	}
+
+	if cfg.UnlockTokenSecret != "" && len(cfg.UnlockTokenSecret) < minSecretLength {
+		return nil, fmt.Errorf("UNLOCK_TOKEN_SECRET must be at least %d characters", minSecretLength)
+	}
+	if cfg.UnlockTokenTTL, err = durationEnv("UNLOCK_TOKEN_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.AdminToken != "" && len(cfg.AdminToken) < minSecretLength {
+		return nil, fmt.Errorf("ADMIN_TOKEN must be at least %d characters", minSecretLength)
+	}
+	if cfg.IPHashSecret != "" && len(cfg.IPHashSecret) < minSecretLength {
+		return nil, fmt.Errorf("IP_HASH_SECRET must be at least %d characters", minSecretLength)
+	}
+	if cfg.PasswordCacheSecret != "" && len(cfg.PasswordCacheSecret) < minSecretLength {
+		return nil, fmt.Errorf("PASSWORD_CACHE_SECRET must be at least %d characters", minSecretLength)
+	}
+
 	return cfg, nil
 }
+
+// loadRateLimits layers the defaults, the optional JSON file and the
+// environment, in that order of increasing precedence.
+func loadRateLimits() (RateLimitConfig, error) {
+	rl := defaultRateLimits
+
+	if path := os.Getenv("RATE_LIMIT_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return rl, fmt.Errorf("reading RATE_LIMIT_CONFIG: %w", err)
+		}
+		var file RateLimitConfig
+		if err := json.Unmarshal(data, &file); err != nil {
+			return rl, fmt.Errorf("parsing RATE_LIMIT_CONFIG: %w", err)
+		}
+		overrideString(&rl.Create, file.Create)
+		overrideString(&rl.Check, file.Check)
+		overrideString(&rl.Unlock, file.Unlock)
+		overrideString(&rl.Redirect, file.Redirect)
+		overrideString(&rl.Expire, file.Expire)
+		overrideString(&rl.Manage, file.Manage)
+		overrideString(&rl.Report, file.Report)
+		overrideString(&rl.Admin, file.Admin)
+		if file.Allowlist != nil {
+			rl.Allowlist = file.Allowlist
+		}
+	}
+
+	overrideString(&rl.Create, os.Getenv("RATE_LIMIT_CREATE"))
+	overrideString(&rl.Check, os.Getenv("RATE_LIMIT_CHECK"))
+	overrideString(&rl.Unlock, os.Getenv("RATE_LIMIT_UNLOCK"))
+	overrideString(&rl.Redirect, os.Getenv("RATE_LIMIT_REDIRECT"))
+	overrideString(&rl.Expire, os.Getenv("RATE_LIMIT_EXPIRE"))
+	overrideString(&rl.Manage, os.Getenv("RATE_LIMIT_MANAGE"))
+	overrideString(&rl.Report, os.Getenv("RATE_LIMIT_REPORT"))
+	overrideString(&rl.Admin, os.Getenv("RATE_LIMIT_ADMIN"))
+	if allow := os.Getenv("RATE_LIMIT_ALLOWLIST"); allow != "" {
+		rl.Allowlist = splitList(allow)
+	}
+
+	return rl, nil
+}
+
+func overrideString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// splitList parses a comma-separated environment value, dropping blanks.
+func splitList(raw string) []string {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func intEnv(key string, def int64) (int64, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return n, nil
+}
+
+func boolEnv(key string) (bool, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false: %w", key, err)
+	}
+	return b, nil
+}
+
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as 15m or 1h: %w", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", key)
+	}
+	return d, nil
+}
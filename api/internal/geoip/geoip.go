@@ -0,0 +1,127 @@
+// Package geoip resolves client IPs to countries from a MaxMind-format
+// database on disk.
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// Locator looks up countries in an .mmdb file. The file can be replaced on
+// disk and reloaded without a restart; lookups in flight keep the old reader
+// until they finish.
+type Locator struct {
+	path string
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+}
+
+// countryRecord is the part of a GeoIP2/GeoLite2 Country or City record the
+// locator needs.
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// Open loads the database at path.
+func Open(path string) (*Locator, error) {
+	l := &Locator{path: path}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload reopens the database file and swaps it in.
+func (l *Locator) Reload() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return fmt.Errorf("reading geoip database: %w", err)
+	}
+	reader, err := maxminddb.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("opening geoip database: %w", err)
+	}
+
+	l.mu.Lock()
+	old := l.reader
+	l.reader = reader
+	l.modTime = info.ModTime()
+	l.mu.Unlock()
+
+	if old != nil {
+		if err := old.Close(); err != nil {
+			slog.Warn("closing previous geoip database", "error", err)
+		}
+	}
+	slog.Info("geoip database loaded", "path", l.path, "built_at", reader.Metadata.BuildTime())
+	return nil
+}
+
+// Watch reloads the database whenever its modification time changes, checking
+// every interval until ctx is cancelled. A failed reload keeps the current
+// database.
+func (l *Locator) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(l.path)
+			if err != nil {
+				slog.Warn("checking geoip database", "error", err)
+				continue
+			}
+			l.mu.RLock()
+			changed := !info.ModTime().Equal(l.modTime)
+			l.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := l.Reload(); err != nil {
+				slog.Warn("reloading geoip database", "error", err)
+			}
+		}
+	}
+}
+
+// Country returns the upper-case ISO 3166-1 alpha-2 code of ip's country, or
+// "" when it is unknown. The registered country is used for addresses the
+// database places on no physical country, such as anycast ranges.
+func (l *Locator) Country(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var record countryRecord
+	if err := l.reader.Lookup(addr.Unmap()).Decode(&record); err != nil {
+		slog.Warn("geoip lookup failed", "error", err)
+		return ""
+	}
+	code := record.Country.ISOCode
+	if code == "" {
+		code = record.RegisteredCountry.ISOCode
+	}
+	return strings.ToUpper(code)
+}
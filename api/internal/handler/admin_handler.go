@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"encurtador/internal/model"
+	"encurtador/internal/service"
+)
+
+// adminServicer is the slice of the URL service the admin API drives.
+type adminServicer interface {
+	AdminInspect(ctx context.Context, slug, actorIP string) (*service.AdminLinkInfo, error)
+	AdminDisable(ctx context.Context, slug, reason, actorIP string) (*service.AdminLinkInfo, error)
+	AdminEnable(ctx context.Context, slug, actorIP string) (*service.AdminLinkInfo, error)
+	AdminExpire(ctx context.Context, slug, actorIP string) (*service.AdminLinkInfo, error)
+	AdminReports(ctx context.Context, slug string, beforeID uint64, limit int, actorIP string) ([]model.AbuseReport, error)
+}
+
+// AdminHandler serves the operator API. Authentication is left to the
+// middleware in front of it.
+type AdminHandler struct {
+	svc adminServicer
+}
+
+func NewAdminHandler(svc adminServicer) *AdminHandler {
+	return &AdminHandler{svc: svc}
+}
+
+type adminLinkResponse struct {
+	linkInfoResponse
+	CreatorID              string `json:"creator_id,omitempty"`
+	ManageTokenFingerprint string `json:"manage_token_fingerprint"`
+	Expired                bool   `json:"expired"`
+	Reports                int64  `json:"reports"`
+}
+
+func newAdminLinkResponse(info *service.AdminLinkInfo) adminLinkResponse {
+	return adminLinkResponse{
+		linkInfoResponse:       newLinkInfoResponse(&info.LinkInfo),
+		CreatorID:              info.CreatorID,
+		ManageTokenFingerprint: info.ManageTokenFingerprint,
+		Expired:                info.Expired,
+		Reports:                info.Reports,
+	}
+}
+
+// GetURL returns any link with its owner details.
+func (h *AdminHandler) GetURL(c *gin.Context) {
+	info, err := h.svc.AdminInspect(c.Request.Context(), c.Param("slug"), c.ClientIP())
+	h.respond(c, info, err)
+}
+
+type disableRequest struct {
+	Reason string `json:"reason"`
+}
+
+// DisableURL takes a link down. The body and its reason are optional.
+func (h *AdminHandler) DisableURL(c *gin.Context) {
+	var req disableRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	info, err := h.svc.AdminDisable(c.Request.Context(), c.Param("slug"), req.Reason, c.ClientIP())
+	h.respond(c, info, err)
+}
+
+// EnableURL lifts a link's disabled state.
+func (h *AdminHandler) EnableURL(c *gin.Context) {
+	info, err := h.svc.AdminEnable(c.Request.Context(), c.Param("slug"), c.ClientIP())
+	h.respond(c, info, err)
+}
+
+// ExpireURL expires a link without its manage token.
+func (h *AdminHandler) ExpireURL(c *gin.Context) {
+	info, err := h.svc.AdminExpire(c.Request.Context(), c.Param("slug"), c.ClientIP())
+	h.respond(c, info, err)
+}
+
+func (h *AdminHandler) respond(c *gin.Context, info *service.AdminLinkInfo, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrLinkNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found"})
+		case errors.Is(err, service.ErrInvalidDisableReason):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, newAdminLinkResponse(info))
+}
+
+type reportResponse struct {
+	ID         uint64             `json:"id"`
+	Slug       string             `json:"slug"`
+	Reason     model.ReportReason `json:"reason"`
+	Details    *string            `json:"details,omitempty"`
+	ReporterID string             `json:"reporter_id"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// ListReports returns abuse reports newest first, optionally for one slug.
+// Pages continue with ?before=<smallest id of the previous page>.
+func (h *AdminHandler) ListReports(c *gin.Context) {
+	var (
+		beforeID uint64
+		limit    int
+		err      error
+	)
+	if raw := c.Query("before"); raw != "" {
+		if beforeID, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before must be a report id"})
+			return
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+	}
+
+	reports, err := h.svc.AdminReports(c.Request.Context(), c.Query("slug"), beforeID, limit, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	resp := make([]reportResponse, len(reports))
+	for i, r := range reports {
+		resp[i] = reportResponse{
+			ID:         r.ID,
+			Slug:       r.Slug,
+			Reason:     r.Reason,
+			Details:    r.Details,
+			ReporterID: r.ReporterID,
+			CreatedAt:  r.CreatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": resp})
+}
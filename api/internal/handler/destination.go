@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"math/rand/v2"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"encurtador/internal/model"
+)
+
+// variantCookieMaxAge is how long a sticky A/B link keeps serving a visitor
+// the variant they first got.
+const variantCookieMaxAge = 30 * 24 * time.Hour
+
+// destination builds where the current visit to cached goes: the target its
+// device or geo rules or its variants pick for the visitor, extended by the
+// path below the short link for prefix links, merged with the visit's query
+// string for passthrough links, and completed with the link's or the server's
+// UTM parameters. It also returns the variant served, if any, and false when
+// the visit carries a path the link does not accept. The target's own query
+// string is kept byte for byte, so signed or order-sensitive targets survive;
+// new parameters are only appended. rest and query are the path below the
+// short link and the visit's query string, as returned by visitPath.
+func (h *URLHandler) destination(c *gin.Context, slug string, cached *model.CachedURL, rest string, query url.Values) (string, string, bool) {
+	rest = strings.TrimPrefix(rest, "/")
+	if rest != "" && (!cached.PrefixMatch || climbsOut(rest)) {
+		return "", "", false
+	}
+	utm := h.defaultUTM
+	if cached.UTM != nil {
+		utm = *cached.UTM
+	}
+	base, variant := h.selectTarget(c, slug, cached)
+	if rest == "" && cached.QueryPassthrough == "" && utm == (model.UTM{}) {
+		return base, variant, true
+	}
+
+	target, err := url.Parse(base)
+	if err != nil {
+		// Targets are validated on creation, so this only guards old rows.
+		return base, variant, true
+	}
+	if rest != "" {
+		joined := target.JoinPath(rest)
+		if !strings.HasPrefix(joined.Path, strings.TrimSuffix(target.Path, "/")+"/") {
+			return "", "", false
+		}
+		target = joined
+	}
+	if cached.QueryPassthrough != "" {
+		query.Del(unlockQueryParam)
+		if len(query) > 0 {
+			target.RawQuery = mergeQuery(target.RawQuery, query, cached.QueryPassthrough)
+		}
+	}
+	// UTM parameters only fill gaps, so explicit ones on the target or the
+	// forwarded visit win.
+	target.RawQuery = appendQuery(target.RawQuery, utm.Missing(target.Query()))
+	return target.String(), variant, true
+}
+
+// visitPath returns the path below the short link and the query string of the
+// current request.
+func visitPath(c *gin.Context) (string, url.Values) {
+	return c.Param("rest"), c.Request.URL.Query()
+}
+
+// climbsOut reports whether the path below a prefix link has ".." segments,
+// which would leave the target's path once resolved.
+func climbsOut(rest string) bool {
+	for _, segment := range strings.Split(rest, "/") {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTarget returns the target for this visitor before any path, query or
+// UTM handling, and the variant it belongs to, if any. Device rules are
+// checked first, then geo rules, then the variants, then the link's default.
+// Geo rules are skipped when no GeoIP database is loaded.
+func (h *URLHandler) selectTarget(c *gin.Context, slug string, cached *model.CachedURL) (string, string) {
+	if len(cached.DeviceRules) > 0 {
+		if target, ok := cached.DeviceRules.Match(platforms(c.Request.UserAgent())); ok {
+			return target, ""
+		}
+	}
+	if len(cached.GeoRules) > 0 && h.geo != nil {
+		// ClientIP honours the router's trusted proxies.
+		if target, ok := cached.GeoRules.Match(h.geo.Country(c.ClientIP())); ok {
+			return target, ""
+		}
+	}
+	if len(cached.Variants) > 0 {
+		v := h.pickVariant(c, slug, cached)
+		return v.TargetURL, v.Name
+	}
+	return cached.TargetURL, ""
+}
+
+// pickVariant chooses a variant by weight. Sticky links first honour the
+// variant remembered in the visitor's cookie, if it still exists, and
+// remember new picks.
+func (h *URLHandler) pickVariant(c *gin.Context, slug string, cached *model.CachedURL) model.Variant {
+	if cached.StickyVariants {
+		if name, err := c.Cookie(variantCookieName); err == nil {
+			if v, ok := cached.Variants.Find(name); ok {
+				return v
+			}
+		}
+	}
+
+	v := cached.Variants.Pick(rand.IntN(cached.Variants.TotalWeight()))
+	if cached.StickyVariants {
+		h.setSlugCookie(c, slug, variantCookieName, v.Name, time.Now().Add(variantCookieMaxAge))
+	}
+	return v
+}
+
+// mergeQuery adds the visit's parameters to the target's raw query. A
+// parameter present on both sides keeps the values of the side named by wins;
+// the target's other pairs are left untouched.
+func mergeQuery(raw string, visit url.Values, wins model.QueryPassthrough) string {
+	target, _ := url.ParseQuery(raw)
+	if wins == model.QueryTargetWins {
+		for key := range visit {
+			if target.Has(key) {
+				visit.Del(key)
+			}
+		}
+		return appendQuery(raw, visit)
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(raw, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); pair == "" || err == nil && visit.Has(name) {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	return appendQuery(strings.Join(kept, "&"), visit)
+}
+
+// appendQuery adds params after the pairs of raw without re-encoding them.
+func appendQuery(raw string, params url.Values) string {
+	switch {
+	case len(params) == 0:
+		return raw
+	case raw == "":
+		return params.Encode()
+	default:
+		return raw + "&" + params.Encode()
+	}
+}
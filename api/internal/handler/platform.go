@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"strings"
+
+	"encurtador/internal/model"
+)
+
+// platforms recognises the visitor's operating system and device class from
+// a User-Agent header. Unknown agents match no platform. iPadOS presents
+// itself as macOS by default and is therefore reported as a desktop.
+func platforms(userAgent string) []model.Platform {
+	var system model.Platform
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		system = model.PlatformIOS
+	case strings.Contains(userAgent, "Android"):
+		system = model.PlatformAndroid
+	case strings.Contains(userAgent, "Windows"):
+		system = model.PlatformWindows
+	case strings.Contains(userAgent, "Macintosh"):
+		system = model.PlatformMacOS
+	case strings.Contains(userAgent, "Linux"), strings.Contains(userAgent, "X11"):
+		system = model.PlatformLinux
+	default:
+		return nil
+	}
+
+	class := model.PlatformDesktop
+	if system == model.PlatformIOS || system == model.PlatformAndroid || strings.Contains(userAgent, "Mobile") {
+		class = model.PlatformMobile
+	}
+	return []model.Platform{system, class}
+}
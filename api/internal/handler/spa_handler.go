@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SPAHandler serves the embedded React frontend. Static files are served from
+// the build output and client-side routes fall back to index.html.
+type SPAHandler struct {
+	files fs.FS
+	index []byte
+}
+
+func NewSPAHandler(files fs.FS) (*SPAHandler, error) {
+	index, err := fs.ReadFile(files, "index.html")
+	if err != nil {
+		return nil, fmt.Errorf("reading embedded index.html: %w", err)
+	}
+	return &SPAHandler{files: files, index: index}, nil
+}
+
+// Index serves index.html for client-side routes. It is never cached so a new
+// deployment is picked up on the next navigation.
+func (h *SPAHandler) Index(c *gin.Context) {
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", h.index)
+}
+
+// File serves a file from the build output at the request path.
+func (h *SPAHandler) File(c *gin.Context) {
+	name := strings.TrimPrefix(c.Request.URL.Path, "/")
+	// Vite fingerprints everything under assets/, so those never change.
+	if strings.HasPrefix(name, "assets/") {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	c.FileFromFS(name, http.FS(h.files))
+}
+
+// Routes returns the router paths that must reach File: one per top-level
+// file, plus a catch-all per top-level directory. Top-level build files all
+// carry an extension, which slugs cannot contain, and directories need a
+// second path segment, so none of these shadow GET /:slug. Directories do
+// shadow /:slug/*rest for a slug of the same name, which is why the service
+// reserves the frontend's directory names.
+func (h *SPAHandler) Routes() ([]string, error) {
+	entries, err := fs.ReadDir(h.files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("listing embedded frontend: %w", err)
+	}
+	var routes []string
+	for _, e := range entries {
+		switch {
+		case e.Name() == "index.html" || strings.HasPrefix(e.Name(), "."):
+		case e.IsDir():
+			routes = append(routes, "/"+e.Name()+"/*filepath")
+		default:
+			routes = append(routes, "/"+e.Name())
+		}
+	}
+	return routes, nil
+}
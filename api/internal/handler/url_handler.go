@@ -3,8 +3,12 @@ package handler
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,33 +22,130 @@ import (
 type urlServicer interface {
 	Create(ctx context.Context, req service.CreateRequest) (*service.CreateResult, error)
 	Resolve(ctx context.Context, slug string) (*model.CachedURL, error)
-	VerifyPassword(ctx context.Context, slug, password string) (string, error)
-	ExpireEarly(ctx context.Context, slug, manageToken string) error
+	VerifyPassword(ctx context.Context, slug, password string) (*service.UnlockResult, error)
+	UnlockTokenValid(slug string, cached *model.CachedURL, token string) bool
+	RecordVisit(ctx context.Context, slug string, cached *model.CachedURL) (bool, error)
+	ExpireEarly(ctx context.Context, slug, manageToken, actorIP string) error
+	Inspect(ctx context.Context, slug, manageToken string) (*service.LinkInfo, error)
+	Update(ctx context.Context, slug, manageToken, actorIP string, req service.UpdateRequest) (*service.LinkInfo, error)
+	ChangePassword(ctx context.Context, slug, manageToken, password, actorIP string) (*service.LinkInfo, error)
+	RotateManageToken(ctx context.Context, slug, manageToken, actorIP string) (string, error)
+	AuditTrail(ctx context.Context, slug, manageToken string, beforeID uint64, limit int) ([]model.AuditEntry, error)
 	CheckSlug(ctx context.Context, slug string) (available bool, suggestion string, err error)
+	LogClick(slug, variant string)
+	Preview(ctx context.Context, slug string) (*service.LinkPreview, error)
+	Report(ctx context.Context, slug string, reason model.ReportReason, details, reporterIP string) error
+}
+
+// countryLocator resolves a client IP to an ISO 3166-1 alpha-2 country code,
+// or "" when unknown.
+type countryLocator interface {
+	Country(ip string) string
+}
+
+const (
+	// unlockCookieName holds a remember-unlock token. The cookie is scoped to
+	// the slug's path, and the token itself is bound to the slug.
+	unlockCookieName = "encurtador_unlock"
+	// unlockQueryParam carries the same token for clients that cannot keep
+	// the cookie, e.g. when the gate runs on a different site.
+	unlockQueryParam = "unlock"
+	// variantCookieName remembers the variant a sticky A/B link served.
+	variantCookieName = "encurtador_variant"
+	// previewSuffix turns a short link into its preview page, e.g. /abc123+.
+	// Slugs cannot contain it, so it never shadows a link.
+	previewSuffix = "+"
+	// pageTimeLayout formats times on the server-rendered pages.
+	pageTimeLayout = "02/01/2006 15:04 UTC"
+	// busyRetryAfter is the Retry-After, in seconds, of unlocks refused
+	// because every password check slot is taken.
+	busyRetryAfter = "1"
+)
+
+// Config holds the settings URLHandler needs beyond its service.
+type Config struct {
+	FrontendURL string
+	// SecureCookies marks cookies Secure and SameSite=None so the frontend can
+	// receive them on cross-site API calls; it requires an https BaseURL.
+	SecureCookies bool
+	// ServerGate renders the password gate and not-found pages from the
+	// embedded templates instead of redirecting to the frontend.
+	ServerGate bool
+	// PendingURL, if set, receives visitors of links that are not active yet
+	// instead of the embedded "not yet available" page.
+	PendingURL string
+	// DefaultRedirectStatus applies to links created without their own
+	// status; RedirectMaxAge bounds how long browsers keep permanent ones.
+	DefaultRedirectStatus int
+	RedirectMaxAge        time.Duration
+	// DefaultUTM fills in campaign parameters for links without their own.
+	DefaultUTM model.UTM
+	// GeoIP resolves visitors' countries for geo rules; nil disables them.
+	GeoIP countryLocator
 }
 
 type URLHandler struct {
-	svc         urlServicer
-	frontendURL string
+	svc           urlServicer
+	frontendURL   string
+	secureCookies bool
+	serverGate    bool
+	pendingURL    string
+	redirect      int
+	maxAge        time.Duration
+	defaultUTM    model.UTM
+	geo           countryLocator
 }
 
-func NewURLHandler(svc urlServicer, frontendURL string) *URLHandler {
-	return &URLHandler{svc: svc, frontendURL: frontendURL}
+func NewURLHandler(svc urlServicer, cfg Config) *URLHandler {
+	return &URLHandler{
+		svc:           svc,
+		frontendURL:   cfg.FrontendURL,
+		secureCookies: cfg.SecureCookies,
+		serverGate:    cfg.ServerGate,
+		pendingURL:    cfg.PendingURL,
+		redirect:      cfg.DefaultRedirectStatus,
+		maxAge:        cfg.RedirectMaxAge,
+		defaultUTM:    cfg.DefaultUTM,
+		geo:           cfg.GeoIP,
+	}
 }
 
 type createRequest struct {
-	TargetURL string    `json:"target_url" binding:"required"`
-	Slug      string    `json:"slug"`
-	TTL       model.TTL `json:"ttl"       binding:"required"`
-	Password  string    `json:"password"`
+	TargetURL      string                  `json:"target_url" binding:"required"`
+	Slug           string                  `json:"slug"`
+	TTL            model.TTL               `json:"ttl"`
+	ExpiresAt      *time.Time              `json:"expires_at"`
+	Password       string                  `json:"password"`
+	MaxClicks      *int64                  `json:"max_clicks"`
+	ActivatesAt    *time.Time              `json:"activates_at"`
+	Sliding        bool                    `json:"sliding"`
+	RedirectStatus *int                    `json:"redirect_status"`
+	ForwardQuery   *model.QueryPassthrough `json:"forward_query"`
+	Prefix         bool                    `json:"prefix"`
+	UTM            *model.UTM              `json:"utm"`
+	DeviceRules    model.DeviceRules       `json:"device_rules"`
+	GeoRules       model.GeoRules          `json:"geo_rules"`
+	Variants       model.Variants          `json:"variants"`
+	StickyVariants bool                    `json:"sticky_variants"`
 }
 
 type createResponse struct {
-	Slug        string    `json:"slug"`
-	ShortURL    string    `json:"short_url"`
-	ExpiresAt   time.Time `json:"expires_at"`
-	Protected   bool      `json:"protected"`
-	ManageToken string    `json:"manage_token"`
+	Slug           string                  `json:"slug"`
+	ShortURL       string                  `json:"short_url"`
+	ExpiresAt      *time.Time              `json:"expires_at"`
+	Protected      bool                    `json:"protected"`
+	MaxClicks      *int64                  `json:"max_clicks,omitempty"`
+	ActivatesAt    *time.Time              `json:"activates_at,omitempty"`
+	SlidingTTL     int64                   `json:"sliding_ttl,omitempty"`
+	RedirectStatus *int                    `json:"redirect_status,omitempty"`
+	ForwardQuery   *model.QueryPassthrough `json:"forward_query,omitempty"`
+	Prefix         bool                    `json:"prefix,omitempty"`
+	UTM            *model.UTM              `json:"utm,omitempty"`
+	DeviceRules    model.DeviceRules       `json:"device_rules,omitempty"`
+	GeoRules       model.GeoRules          `json:"geo_rules,omitempty"`
+	Variants       model.Variants          `json:"variants,omitempty"`
+	StickyVariants bool                    `json:"sticky_variants,omitempty"`
+	ManageToken    string                  `json:"manage_token"`
 }
 
 func (h *URLHandler) CreateURL(c *gin.Context) {
@@ -58,12 +159,29 @@ func (h *URLHandler) CreateURL(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "target_url: " + err.Error()})
 		return
 	}
+	if err := validateRuleTargets(req.DeviceRules, req.GeoRules, req.Variants); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	result, err := h.svc.Create(c.Request.Context(), service.CreateRequest{
-		TargetURL: req.TargetURL,
-		Slug:      req.Slug,
-		TTL:       req.TTL,
-		Password:  req.Password,
+		TargetURL:        req.TargetURL,
+		Slug:             req.Slug,
+		TTL:              req.TTL,
+		Password:         req.Password,
+		MaxClicks:        req.MaxClicks,
+		ActivatesAt:      req.ActivatesAt,
+		ExpiresAt:        req.ExpiresAt,
+		Sliding:          req.Sliding,
+		RedirectStatus:   req.RedirectStatus,
+		QueryPassthrough: req.ForwardQuery,
+		PrefixMatch:      req.Prefix,
+		UTM:              req.UTM,
+		DeviceRules:      req.DeviceRules,
+		GeoRules:         req.GeoRules,
+		Variants:         req.Variants,
+		StickyVariants:   req.StickyVariants,
+		CreatorIP:        c.ClientIP(),
 	})
 	if err != nil {
 		switch {
@@ -72,7 +190,17 @@ func (h *URLHandler) CreateURL(c *gin.Context) {
 		case errors.Is(err, service.ErrInvalidSlugFormat):
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		case errors.Is(err, service.ErrInvalidTTL):
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ttl value"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ttl value", "detail": err.Error()})
+		case errors.Is(err, service.ErrInvalidMaxClicks), errors.Is(err, service.ErrInvalidActivation),
+			errors.Is(err, service.ErrInvalidSliding), errors.Is(err, service.ErrInvalidRedirect),
+			errors.Is(err, service.ErrInvalidPassthrough), errors.Is(err, service.ErrInvalidUTM),
+			errors.Is(err, service.ErrInvalidDeviceRules), errors.Is(err, service.ErrInvalidGeoRules),
+			errors.Is(err, service.ErrInvalidVariants), errors.Is(err, service.ErrSelfReferential),
+			errors.Is(err, service.ErrShortenerTarget), errors.Is(err, service.ErrPasswordTooLong):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrDestinationBlocked), errors.Is(err, service.ErrPrivateTarget),
+			errors.Is(err, service.ErrUnresolvableTarget), errors.Is(err, service.ErrMaliciousTarget):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": refusalCode(err)})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create URL"})
 		}
@@ -80,11 +208,22 @@ func (h *URLHandler) CreateURL(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, createResponse{
-		Slug:        result.Slug,
-		ShortURL:    result.ShortURL,
-		ExpiresAt:   result.ExpiresAt,
-		Protected:   result.Protected,
-		ManageToken: result.ManageToken,
+		Slug:           result.Slug,
+		ShortURL:       result.ShortURL,
+		ExpiresAt:      result.ExpiresAt,
+		Protected:      result.Protected,
+		MaxClicks:      result.MaxClicks,
+		ActivatesAt:    result.ActivatesAt,
+		SlidingTTL:     int64(result.SlidingTTL / time.Second),
+		RedirectStatus: result.RedirectStatus,
+		ForwardQuery:   result.QueryPassthrough,
+		Prefix:         result.PrefixMatch,
+		UTM:            result.UTM,
+		DeviceRules:    result.DeviceRules,
+		GeoRules:       result.GeoRules,
+		Variants:       result.Variants,
+		StickyVariants: result.StickyVariants,
+		ManageToken:    result.ManageToken,
 	})
 }
 
@@ -110,6 +249,10 @@ func (h *URLHandler) CheckSlug(c *gin.Context) {
 
 func (h *URLHandler) RedirectOrGate(c *gin.Context) {
 	slug := c.Param("slug")
+	if slug, ok := strings.CutSuffix(slug, previewSuffix); ok {
+		h.Preview(c, slug)
+		return
+	}
 
 	cached, err := h.svc.Resolve(c.Request.Context(), slug)
 	if err != nil {
@@ -117,20 +260,331 @@ func (h *URLHandler) RedirectOrGate(c *gin.Context) {
 		return
 	}
 	if cached == nil {
-		c.Redirect(http.StatusFound, h.frontendURL+"/404")
+		h.notFound(c)
+		return
+	}
+	if cached.Disabled {
+		h.disabled(c, slug)
+		return
+	}
+	if cached.Pending(time.Now()) {
+		h.notActive(c, slug, *cached.ActivatesAt)
+		return
+	}
+	// The gate comes first: picking a destination assigns an A/B variant,
+	// which visitors without the password must not be given or counted in.
+	if cached.Protected && !h.svc.UnlockTokenValid(slug, cached, h.unlockToken(c)) {
+		h.gate(c, slug)
+		return
+	}
+	rest, query := visitPath(c)
+	target, variant, ok := h.destination(c, slug, cached, rest, query)
+	if !ok {
+		h.notFound(c)
+		return
+	}
+
+	ok, err = h.svc.RecordVisit(c.Request.Context(), slug, cached)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if !ok {
+		h.notFound(c)
+		return
+	}
+	h.svc.LogClick(slug, variant)
+
+	status := h.redirectStatus(cached)
+	switch {
+	case cached.MustReachServer():
+		c.Header("Cache-Control", "private, no-store")
+	case model.PermanentRedirect(status):
+		// Browsers keep permanent redirects indefinitely unless told
+		// otherwise; bound it so edits and early expiry eventually apply.
+		// Per-visitor destinations may only be kept by the visitor's own
+		// browser, never by a shared cache.
+		scope := "public"
+		if cached.VariesByVisitor() {
+			scope = "private"
+		}
+		c.Header("Cache-Control", scope+", max-age="+strconv.FormatInt(int64(h.maxAge/time.Second), 10))
+	default:
+		c.Header("Cache-Control", "no-cache")
+	}
+	if len(cached.DeviceRules) > 0 {
+		c.Header("Vary", "User-Agent")
+	}
+	c.Redirect(status, target)
+}
+
+// redirectStatus picks the status for a redirect to cached's target: the
+// link's own or the server default, downgraded to its temporary counterpart
+// (keeping the method semantics) for links whose visits must reach the server.
+func (h *URLHandler) redirectStatus(cached *model.CachedURL) int {
+	status := h.redirect
+	if cached.RedirectStatus != 0 {
+		status = cached.RedirectStatus
+	}
+	if cached.MustReachServer() {
+		switch status {
+		case http.StatusMovedPermanently:
+			return http.StatusFound
+		case http.StatusPermanentRedirect:
+			return http.StatusTemporaryRedirect
+		}
+	}
+	return status
+}
+
+type previewPage struct {
+	Slug            string
+	Host            string
+	TargetURL       string
+	ExpiresAt       string
+	ExpiresAtISO    string
+	ActivatesAt     string
+	ActivatesAtISO  string
+	Protected       bool
+	LimitedClicks   bool
+	VariesByVisitor bool
+}
+
+// Preview renders what a short link leads to without following it or
+// counting a visit. The target of protected, click-limited and not yet
+// active links is never shown.
+func (h *URLHandler) Preview(c *gin.Context, slug string) {
+	preview, err := h.svc.Preview(c.Request.Context(), slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if preview == nil {
+		h.notFound(c)
+		return
+	}
+	if preview.Disabled {
+		h.disabled(c, slug)
+		return
+	}
+
+	page := previewPage{
+		Slug:            preview.Slug,
+		TargetURL:       preview.TargetURL,
+		Protected:       preview.Protected,
+		LimitedClicks:   preview.LimitedClicks,
+		VariesByVisitor: preview.VariesByVisitor,
+	}
+	if preview.TargetURL != "" {
+		if u, err := url.Parse(preview.TargetURL); err == nil {
+			page.Host = u.Hostname()
+		}
+	}
+	if preview.ExpiresAt != nil {
+		page.ExpiresAt = preview.ExpiresAt.UTC().Format(pageTimeLayout)
+		page.ExpiresAtISO = preview.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if preview.ActivatesAt != nil {
+		page.ActivatesAt = preview.ActivatesAt.UTC().Format(pageTimeLayout)
+		page.ActivatesAtISO = preview.ActivatesAt.UTC().Format(time.RFC3339)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.HTML(http.StatusOK, "preview.html", page)
+}
+
+func (h *URLHandler) notFound(c *gin.Context) {
+	if h.serverGate {
+		c.HTML(http.StatusNotFound, "not_found.html", nil)
+		return
+	}
+	c.Redirect(http.StatusFound, h.frontendURL+"/404")
+}
+
+type disabledPage struct {
+	Slug string
+}
+
+// disabled answers visits to a link taken down by an operator or a threat
+// list scan. The page is always rendered by the server, so the frontend
+// needs no route for it.
+func (h *URLHandler) disabled(c *gin.Context, slug string) {
+	c.Header("Cache-Control", "no-store")
+	c.HTML(http.StatusGone, "disabled.html", disabledPage{Slug: slug})
+}
+
+type notActivePage struct {
+	Slug           string
+	ActivatesAt    string
+	ActivatesAtISO string
+}
+
+// notActive answers visits to a link before its activation time, either by
+// redirecting to the configured pending page or with the embedded one.
+func (h *URLHandler) notActive(c *gin.Context, slug string, activatesAt time.Time) {
+	c.Header("Cache-Control", "no-store")
+	if h.pendingURL != "" {
+		q := url.Values{}
+		q.Set("slug", slug)
+		q.Set("activates_at", activatesAt.UTC().Format(time.RFC3339))
+		c.Redirect(http.StatusFound, h.pendingURL+"?"+q.Encode())
+		return
+	}
+	c.HTML(http.StatusForbidden, "not_active.html", notActivePage{
+		Slug:           slug,
+		ActivatesAt:    activatesAt.UTC().Format(pageTimeLayout),
+		ActivatesAtISO: activatesAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// gate asks for the password of a protected link. The frontend gate gets the
+// path below the short link and the visit's query string as the rest and
+// query parameters, and hands them back to UnlockURL, so prefix and
+// passthrough links survive it.
+func (h *URLHandler) gate(c *gin.Context, slug string) {
+	if h.serverGate {
+		h.renderGate(c, http.StatusOK, slug, "")
+		return
+	}
+	location := h.frontendURL + "/gate/" + slug
+	q := url.Values{}
+	if rest := strings.TrimPrefix(c.Param("rest"), "/"); rest != "" {
+		q.Set("rest", rest)
+	}
+	visit := c.Request.URL.Query()
+	visit.Del(unlockQueryParam)
+	if len(visit) > 0 {
+		q.Set("query", visit.Encode())
+	}
+	if len(q) > 0 {
+		location += "?" + q.Encode()
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+type gatePage struct {
+	Slug   string
+	Action string
+	Error  string
+}
+
+// renderGate shows the password form, which posts back to the visited URL so
+// the path and query string of a prefix or passthrough link survive the gate.
+func (h *URLHandler) renderGate(c *gin.Context, status int, slug, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.HTML(status, "gate.html", gatePage{Slug: slug, Action: c.Request.URL.RequestURI(), Error: message})
+}
+
+// SubmitGate handles the form posted by the server-rendered gate. On success
+// it remembers the unlock and sends the visitor to the target; otherwise it
+// renders the gate again with the reason.
+func (h *URLHandler) SubmitGate(c *gin.Context) {
+	slug := c.Param("slug")
+
+	result, err := h.svc.VerifyPassword(c.Request.Context(), slug, c.PostForm("password"))
+	if err != nil {
+		var (
+			lockout   *service.LockoutError
+			notActive *service.NotActiveError
+		)
+		switch {
+		case errors.As(err, &notActive):
+			h.notActive(c, slug, notActive.ActivatesAt)
+		case errors.Is(err, service.ErrLinkDisabled):
+			h.disabled(c, slug)
+		case errors.Is(err, service.ErrInvalidPassword):
+			h.renderGate(c, http.StatusUnauthorized, slug, "Senha incorreta.")
+		case errors.Is(err, service.ErrPasswordTooLong):
+			h.renderGate(c, http.StatusBadRequest, slug, "Senha longa demais.")
+		case errors.Is(err, service.ErrUnlockBusy):
+			c.Header("Retry-After", busyRetryAfter)
+			h.renderGate(c, http.StatusServiceUnavailable, slug, "Servidor ocupado. Tente novamente em instantes.")
+		case errors.As(err, &lockout):
+			retryAfter := retryAfterSeconds(lockout.RetryAfter)
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			h.renderGate(c, http.StatusTooManyRequests, slug,
+				fmt.Sprintf("Muitas tentativas incorretas. Tente novamente em %s.", formatWait(retryAfter)))
+		default:
+			h.renderGate(c, http.StatusInternalServerError, slug, "Erro interno. Tente novamente mais tarde.")
+		}
+		return
+	}
+	if result == nil {
+		c.HTML(http.StatusNotFound, "not_found.html", nil)
 		return
 	}
 
-	if cached.Protected {
-		c.Redirect(http.StatusFound, h.frontendURL+"/gate/"+slug)
+	rest, query := visitPath(c)
+	target, variant, ok := h.destination(c, slug, result.Link, rest, query)
+	if ok {
+		ok, err = h.svc.RecordVisit(c.Request.Context(), slug, result.Link)
+	}
+	if err != nil {
+		h.renderGate(c, http.StatusInternalServerError, slug, "Erro interno. Tente novamente mais tarde.")
+		return
+	}
+	if !ok {
+		c.HTML(http.StatusNotFound, "not_found.html", nil)
 		return
 	}
+	h.svc.LogClick(slug, variant)
+
+	if result.Token != "" {
+		h.setUnlockCookie(c, slug, result.Token, result.TokenExpiresAt)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusSeeOther, target)
+}
 
-	c.Redirect(http.StatusMovedPermanently, cached.TargetURL)
+func retryAfterSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// formatWait renders a wait in seconds as Portuguese text for the gate page.
+func formatWait(seconds int64) string {
+	if seconds < 60 {
+		return fmt.Sprintf("%d segundos", seconds)
+	}
+	return fmt.Sprintf("%d minutos", (seconds+59)/60)
 }
 
+// unlockToken returns the remember-unlock token from the query string or,
+// failing that, from the slug-scoped cookie.
+func (h *URLHandler) unlockToken(c *gin.Context) string {
+	if token := c.Query(unlockQueryParam); token != "" {
+		return token
+	}
+	token, _ := c.Cookie(unlockCookieName)
+	return token
+}
+
+func (h *URLHandler) setUnlockCookie(c *gin.Context, slug, token string, expiresAt time.Time) {
+	h.setSlugCookie(c, slug, unlockCookieName, token, expiresAt)
+}
+
+// setSlugCookie sets an HttpOnly cookie scoped to the short link's path.
+func (h *URLHandler) setSlugCookie(c *gin.Context, slug, name, value string, expiresAt time.Time) {
+	sameSite := http.SameSiteLaxMode
+	if h.secureCookies {
+		sameSite = http.SameSiteNoneMode
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/" + slug,
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		Secure:   h.secureCookies,
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
+}
+
+// unlockRequest is posted by the frontend gate. Rest and Query carry the
+// path below the short link and the visit's query string it was given.
 type unlockRequest struct {
 	Password string `json:"password"`
+	Rest     string `json:"rest"`
+	Query    string `json:"query"`
 }
 
 func (h *URLHandler) UnlockURL(c *gin.Context) {
@@ -141,22 +595,79 @@ func (h *URLHandler) UnlockURL(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	query, err := url.ParseQuery(req.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query: " + err.Error()})
+		return
+	}
 
-	targetURL, err := h.svc.VerifyPassword(c.Request.Context(), slug, req.Password)
+	result, err := h.svc.VerifyPassword(c.Request.Context(), slug, req.Password)
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidPassword) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid password"})
 			return
 		}
+		if errors.Is(err, service.ErrPasswordTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrUnlockBusy) {
+			c.Header("Retry-After", busyRetryAfter)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": service.ErrUnlockBusy.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrLinkDisabled) {
+			c.JSON(http.StatusGone, gin.H{"error": "link has been disabled"})
+			return
+		}
+		var notActive *service.NotActiveError
+		if errors.As(err, &notActive) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":        "link is not active yet",
+				"activates_at": notActive.ActivatesAt,
+			})
+			return
+		}
+		var lockout *service.LockoutError
+		if errors.As(err, &lockout) {
+			retryAfter := retryAfterSeconds(lockout.RetryAfter)
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "too many failed attempts",
+				"retry_after": retryAfter,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 		return
 	}
-	if targetURL == "" {
+	if result == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found or expired"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"target_url": targetURL})
+	// The frontend gate sends the visitor straight to target_url, so it gets
+	// the same per-visitor target and UTM parameters as a redirect would.
+	target, variant, ok := h.destination(c, slug, result.Link, req.Rest, query)
+	if ok {
+		ok, err = h.svc.RecordVisit(c.Request.Context(), slug, result.Link)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL not found or expired"})
+		return
+	}
+	h.svc.LogClick(slug, variant)
+	resp := gin.H{"target_url": target}
+	if result.Token != "" {
+		h.setUnlockCookie(c, slug, result.Token, result.TokenExpiresAt)
+		resp["unlock_token"] = result.Token
+		resp["unlock_expires_at"] = result.TokenExpiresAt
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 type expireRequest struct {
@@ -172,7 +683,7 @@ func (h *URLHandler) ExpireURL(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.ExpireEarly(c.Request.Context(), slug, req.ManageToken); err != nil {
+	if err := h.svc.ExpireEarly(c.Request.Context(), slug, req.ManageToken, c.ClientIP()); err != nil {
 		if errors.Is(err, service.ErrInvalidManageToken) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid manage token"})
 			return
@@ -184,6 +695,339 @@ func (h *URLHandler) ExpireURL(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "URL has been expired"})
 }
 
+type reportRequest struct {
+	Reason  model.ReportReason `json:"reason" binding:"required"`
+	Details string             `json:"details"`
+}
+
+// ReportURL records a public abuse report against a link for operators to
+// review.
+func (h *URLHandler) ReportURL(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req reportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.svc.Report(c.Request.Context(), slug, req.Reason, req.Details, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidReport):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrLinkNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "URL not found or expired"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"reported": true})
+}
+
+type linkInfoRequest struct {
+	ManageToken string `json:"manage_token" binding:"required"`
+}
+
+type linkInfoResponse struct {
+	Slug            string                  `json:"slug"`
+	TargetURL       string                  `json:"target_url"`
+	ExpiresAt       *time.Time              `json:"expires_at"`
+	CreatedAt       time.Time               `json:"created_at"`
+	Protected       bool                    `json:"protected"`
+	MaxClicks       *int64                  `json:"max_clicks,omitempty"`
+	ClicksRemaining *int64                  `json:"clicks_remaining,omitempty"`
+	ActivatesAt     *time.Time              `json:"activates_at,omitempty"`
+	SlidingTTL      int64                   `json:"sliding_ttl,omitempty"`
+	RedirectStatus  *int                    `json:"redirect_status,omitempty"`
+	ForwardQuery    *model.QueryPassthrough `json:"forward_query,omitempty"`
+	Prefix          bool                    `json:"prefix,omitempty"`
+	UTM             *model.UTM              `json:"utm,omitempty"`
+	DeviceRules     model.DeviceRules       `json:"device_rules,omitempty"`
+	GeoRules        model.GeoRules          `json:"geo_rules,omitempty"`
+	Variants        model.Variants          `json:"variants,omitempty"`
+	StickyVariants  bool                    `json:"sticky_variants,omitempty"`
+	Clicks          int64                   `json:"clicks"`
+	VariantClicks   map[string]int64        `json:"variant_clicks,omitempty"`
+	DisabledAt      *time.Time              `json:"disabled_at,omitempty"`
+	DisabledReason  string                  `json:"disabled_reason,omitempty"`
+	FailedAttempts  int64                   `json:"failed_attempts"`
+	LockedUntil     *time.Time              `json:"locked_until,omitempty"`
+}
+
+// LinkInfo returns owner-only details about a link to holders of its manage token.
+func (h *URLHandler) LinkInfo(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req linkInfoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	info, err := h.svc.Inspect(c.Request.Context(), slug, req.ManageToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidManageToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid manage token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newLinkInfoResponse(info))
+}
+
+func newLinkInfoResponse(info *service.LinkInfo) linkInfoResponse {
+	resp := linkInfoResponse{
+		Slug:            info.Slug,
+		TargetURL:       info.TargetURL,
+		ExpiresAt:       info.ExpiresAt,
+		CreatedAt:       info.CreatedAt,
+		Protected:       info.Protected,
+		MaxClicks:       info.MaxClicks,
+		ClicksRemaining: info.ClicksRemaining,
+		ActivatesAt:     info.ActivatesAt,
+		SlidingTTL:      int64(info.SlidingTTL / time.Second),
+		RedirectStatus:  info.RedirectStatus,
+		ForwardQuery:    info.QueryPassthrough,
+		Prefix:          info.PrefixMatch,
+		UTM:             info.UTM,
+		DeviceRules:     info.DeviceRules,
+		GeoRules:        info.GeoRules,
+		Variants:        info.Variants,
+		StickyVariants:  info.StickyVariants,
+		Clicks:          info.Clicks,
+		VariantClicks:   info.VariantClicks,
+		DisabledAt:      info.DisabledAt,
+		DisabledReason:  info.DisabledReason,
+		FailedAttempts:  info.FailedAttempts,
+	}
+	if info.LockedFor > 0 {
+		until := time.Now().Add(info.LockedFor)
+		resp.LockedUntil = &until
+	}
+	return resp
+}
+
+type updateRequest struct {
+	ManageToken    string             `json:"manage_token" binding:"required"`
+	TargetURL      *string            `json:"target_url"`
+	UTM            *model.UTM         `json:"utm"`
+	UTMDefault     bool               `json:"utm_default"`
+	DeviceRules    *model.DeviceRules `json:"device_rules"`
+	GeoRules       *model.GeoRules    `json:"geo_rules"`
+	Variants       *model.Variants    `json:"variants"`
+	StickyVariants *bool              `json:"sticky_variants"`
+}
+
+// UpdateURL changes the editable fields of a link for holders of its manage
+// token and answers with the link's new owner view.
+func (h *URLHandler) UpdateURL(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req updateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TargetURL != nil {
+		if err := validateHTTPURL(*req.TargetURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "target_url: " + err.Error()})
+			return
+		}
+	}
+	var (
+		deviceRules model.DeviceRules
+		geoRules    model.GeoRules
+		variants    model.Variants
+	)
+	if req.DeviceRules != nil {
+		deviceRules = *req.DeviceRules
+	}
+	if req.GeoRules != nil {
+		geoRules = *req.GeoRules
+	}
+	if req.Variants != nil {
+		variants = *req.Variants
+	}
+	if err := validateRuleTargets(deviceRules, geoRules, variants); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	info, err := h.svc.Update(c.Request.Context(), slug, req.ManageToken, c.ClientIP(), service.UpdateRequest{
+		TargetURL:      req.TargetURL,
+		UTM:            req.UTM,
+		UTMDefault:     req.UTMDefault,
+		DeviceRules:    req.DeviceRules,
+		GeoRules:       req.GeoRules,
+		Variants:       req.Variants,
+		StickyVariants: req.StickyVariants,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidManageToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid manage token"})
+		case errors.Is(err, service.ErrInvalidUTM), errors.Is(err, service.ErrUTMConflict),
+			errors.Is(err, service.ErrInvalidDeviceRules),
+			errors.Is(err, service.ErrInvalidGeoRules), errors.Is(err, service.ErrInvalidVariants),
+			errors.Is(err, service.ErrSelfReferential), errors.Is(err, service.ErrShortenerTarget):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrDestinationBlocked), errors.Is(err, service.ErrPrivateTarget),
+			errors.Is(err, service.ErrUnresolvableTarget), errors.Is(err, service.ErrMaliciousTarget):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": refusalCode(err)})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, newLinkInfoResponse(info))
+}
+
+// refusalCode names the policy that refused a link's target, so clients can
+// tell the 422 answers apart without parsing the message.
+func refusalCode(err error) string {
+	switch {
+	case errors.Is(err, service.ErrDestinationBlocked):
+		return "domain_blocked"
+	case errors.Is(err, service.ErrPrivateTarget):
+		return "private_target"
+	case errors.Is(err, service.ErrUnresolvableTarget):
+		return "unresolvable_target"
+	default:
+		return "malicious_target"
+	}
+}
+
+type passwordRequest struct {
+	ManageToken string `json:"manage_token" binding:"required"`
+	Password    string `json:"password"`
+}
+
+// ChangePassword sets or, with an empty password, removes the password of a
+// link for holders of its manage token.
+func (h *URLHandler) ChangePassword(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req passwordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	info, err := h.svc.ChangePassword(c.Request.Context(), slug, req.ManageToken, req.Password, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidManageToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid manage token"})
+		case errors.Is(err, service.ErrPasswordTooLong):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, newLinkInfoResponse(info))
+}
+
+// RotateToken replaces the manage token of a link; the one sent stops
+// working.
+func (h *URLHandler) RotateToken(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req linkInfoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.svc.RotateManageToken(c.Request.Context(), slug, req.ManageToken, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidManageToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid manage token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"manage_token": token})
+}
+
+type auditRequest struct {
+	ManageToken string `json:"manage_token" binding:"required"`
+	Before      uint64 `json:"before"`
+	Limit       int    `json:"limit" binding:"min=0"`
+}
+
+type auditEntryResponse struct {
+	ID        uint64            `json:"id"`
+	Action    model.AuditAction `json:"action"`
+	Actor     model.AuditActor  `json:"actor"`
+	ActorID   *string           `json:"actor_id,omitempty"`
+	Before    model.AuditState  `json:"before,omitempty"`
+	After     model.AuditState  `json:"after,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// AuditTrail returns the audit log of a link, newest first, to holders of
+// its manage token. Pages continue with "before" set to the smallest id of
+// the previous page.
+func (h *URLHandler) AuditTrail(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req auditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.svc.AuditTrail(c.Request.Context(), slug, req.ManageToken, req.Before, req.Limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidManageToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid manage token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	resp := make([]auditEntryResponse, len(entries))
+	for i, e := range entries {
+		resp[i] = auditEntryResponse{
+			ID:        e.ID,
+			Action:    e.Action,
+			Actor:     e.Actor,
+			ActorID:   e.ActorID,
+			Before:    e.Before,
+			After:     e.After,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": resp})
+}
+
+// validateRuleTargets checks the target of every device rule, geo rule and
+// variant the way target_url itself is checked.
+func validateRuleTargets(device model.DeviceRules, geo model.GeoRules, variants model.Variants) error {
+	for i, rule := range device {
+		if err := validateHTTPURL(rule.TargetURL); err != nil {
+			return fmt.Errorf("device_rules[%d].target_url: %w", i, err)
+		}
+	}
+	for i, rule := range geo {
+		if err := validateHTTPURL(rule.TargetURL); err != nil {
+			return fmt.Errorf("geo_rules[%d].target_url: %w", i, err)
+		}
+	}
+	for i, v := range variants {
+		if err := validateHTTPURL(v.TargetURL); err != nil {
+			return fmt.Errorf("variants[%d].target_url: %w", i, err)
+		}
+	}
+	return nil
+}
+
 func validateHTTPURL(raw string) error {
 	u, err := url.Parse(raw)
 	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
@@ -1,28 +1,110 @@
 package middleware
 
 import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ulule/limiter/v3"
-	limitergin "github.com/ulule/limiter/v3/drivers/middleware/gin"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
 )
 
-const (
-	rateLimitPeriod   = time.Minute
-	rateLimitRequests = 60
-)
-
 // NewRateLimiter returns a Gin middleware that enforces a per-IP request cap
-// on the URL shortener's public endpoints. For global limits across services,
-// prefer configuring the gateway or reverse proxy layer instead.
-func NewRateLimiter() gin.HandlerFunc {
-	rate := limiter.Rate{
-		Period: rateLimitPeriod,
-		Limit:  rateLimitRequests,
+// described in limiter's "<limit>-<period>" format (e.g. "60-M"). Every call
+// owns an independent in-memory budget, so routes limited by different
+// middlewares never consume each other's allowance. Clients matched by allow
+// bypass the limit entirely. For global limits across services, prefer
+// configuring the gateway or reverse proxy layer instead.
+func NewRateLimiter(formatted string, allow *Allowlist) (gin.HandlerFunc, error) {
+	rate, err := limiter.NewRateFromFormatted(formatted)
+	if err != nil {
+		return nil, fmt.Errorf("parsing rate %q: %w", formatted, err)
+	}
+	instance := limiter.New(memory.NewStore(), rate)
+	policy := fmt.Sprintf("%d;w=%d", rate.Limit, int64(rate.Period/time.Second))
+
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if allow.Contains(ip) {
+			c.Next()
+			return
+		}
+
+		lctx, err := instance.Get(c.Request.Context(), ip)
+		if err != nil {
+			// Fail open: a broken limiter store must not take redirects down.
+			slog.Warn("rate limiter lookup failed", "error", err)
+			c.Next()
+			return
+		}
+
+		resetIn := max(lctx.Reset-time.Now().Unix(), 0)
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.FormatInt(lctx.Limit, 10))
+		c.Header("RateLimit-Remaining", strconv.FormatInt(lctx.Remaining, 10))
+		c.Header("RateLimit-Reset", strconv.FormatInt(resetIn, 10))
+
+		if lctx.Reached {
+			c.Header("Retry-After", strconv.FormatInt(max(resetIn, 1), 10))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": http.StatusText(http.StatusTooManyRequests)})
+			return
+		}
+		c.Next()
+	}, nil
+}
+
+// Allowlist is a set of IP addresses and CIDR ranges exempt from rate limits.
+// A nil Allowlist matches nothing.
+type Allowlist struct {
+	prefixes []netip.Prefix
+}
+
+// NewAllowlist parses entries that are either bare IP addresses or CIDR
+// ranges. Blank entries are ignored.
+func NewAllowlist(entries []string) (*Allowlist, error) {
+	a := &Allowlist{}
+	for _, raw := range entries {
+		entry := strings.TrimSpace(raw)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("parsing allowlist cidr %q: %w", entry, err)
+			}
+			a.prefixes = append(a.prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("parsing allowlist ip %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		a.prefixes = append(a.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return a, nil
+}
+
+// Contains reports whether ip falls inside any allowlisted range.
+func (a *Allowlist) Contains(ip string) bool {
+	if a == nil || len(a.prefixes) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range a.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
 	}
-	store := memory.NewStore()
-	instance := limiter.New(store, rate)
-	return limitergin.NewMiddleware(instance)
+	return false
 }
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// AuditAction names what an audit entry records.
+type AuditAction string
+
+const (
+	AuditCreate      AuditAction = "create"
+	AuditUpdate      AuditAction = "update"
+	AuditPassword    AuditAction = "password"
+	AuditRotateToken AuditAction = "rotate_token"
+	AuditView        AuditAction = "view"
+	AuditExpire      AuditAction = "expire"
+	AuditDisable     AuditAction = "disable"
+	AuditEnable      AuditAction = "enable"
+	// AuditViewReports records an operator reading abuse reports; its slug
+	// is empty when the listing was not filtered by link.
+	AuditViewReports AuditAction = "view_reports"
+)
+
+// AuditActor is who took an audited action: the link's owner through its
+// manage token, an operator through the admin API, or the server itself.
+type AuditActor string
+
+const (
+	ActorOwner  AuditActor = "owner"
+	ActorAdmin  AuditActor = "admin"
+	ActorSystem AuditActor = "system"
+)
+
+// AuditEntry is a row of the audit_log table. Before and After hold the
+// link's state around the action as JSON, and are nil for actions that do
+// not change it. ActorID is a keyed hash of the actor's IP, so entries from
+// one client can be told apart without storing its address; it is nil for
+// system actions.
+type AuditEntry struct {
+	ID        uint64      `db:"id"`
+	URLID     *uint64     `db:"url_id"`
+	Slug      string      `db:"slug"`
+	Action    AuditAction `db:"action"`
+	Actor     AuditActor  `db:"actor"`
+	ActorID   *string     `db:"actor_id"`
+	Before    AuditState  `db:"state_before"`
+	After     AuditState  `db:"state_after"`
+	CreatedAt time.Time   `db:"created_at"`
+}
+
+// AuditState is a JSON snapshot of a link, kept verbatim; empty means none.
+type AuditState []byte
+
+func (s AuditState) MarshalJSON() ([]byte, error) {
+	if len(s) == 0 {
+		return []byte("null"), nil
+	}
+	return s, nil
+}
+
+func (s AuditState) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	return []byte(s), nil
+}
+
+func (s *AuditState) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+	case []byte:
+		*s = append(AuditState(nil), v...)
+	case string:
+		*s = AuditState(v)
+	default:
+		return fmt.Errorf("scanning audit state: unsupported type %T", src)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// Platform is a client platform recognised from the User-Agent header.
+// Operating systems and the broader mobile/desktop classes can both be
+// targeted; a visitor on an iPhone matches both PlatformIOS and
+// PlatformMobile.
+type Platform string
+
+const (
+	PlatformIOS     Platform = "ios"
+	PlatformAndroid Platform = "android"
+	PlatformWindows Platform = "windows"
+	PlatformMacOS   Platform = "macos"
+	PlatformLinux   Platform = "linux"
+	PlatformMobile  Platform = "mobile"
+	PlatformDesktop Platform = "desktop"
+)
+
+// ValidPlatforms lists the platforms a device rule may target.
+var ValidPlatforms = map[Platform]bool{
+	PlatformIOS:     true,
+	PlatformAndroid: true,
+	PlatformWindows: true,
+	PlatformMacOS:   true,
+	PlatformLinux:   true,
+	PlatformMobile:  true,
+	PlatformDesktop: true,
+}
+
+// DeviceRule sends visitors on Platform to TargetURL instead of the link's
+// default target.
+type DeviceRule struct {
+	Platform  Platform `json:"platform"`
+	TargetURL string   `json:"target_url"`
+}
+
+// DeviceRules are evaluated in order and the first match wins. They are
+// stored as a JSON column; an empty list is stored as NULL.
+type DeviceRules []DeviceRule
+
+// Match returns the target of the first rule whose platform is among the
+// visitor's, or false when none applies.
+func (r DeviceRules) Match(platforms []Platform) (string, bool) {
+	for _, rule := range r {
+		for _, p := range platforms {
+			if rule.Platform == p {
+				return rule.TargetURL, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (r DeviceRules) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *DeviceRules) Scan(src any) error {
+	if src == nil {
+		*r = nil
+		return nil
+	}
+	return scanJSON(src, r, "device rules")
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// DomainList names the list a DomainRule belongs to.
+type DomainList string
+
+const (
+	// DomainBlock rejects destinations matching the rule.
+	DomainBlock DomainList = "block"
+	// DomainAllow, once any allow rule exists, rejects every destination
+	// that matches none of them.
+	DomainAllow DomainList = "allow"
+)
+
+// DomainRule is an operator-managed restriction on link destinations. Pattern
+// is an exact host, a "*." wildcard matching every subdomain of a host, or a
+// CIDR matching IP-literal hosts.
+type DomainRule struct {
+	ID        uint64     `db:"id"`
+	Pattern   string     `db:"pattern"`
+	List      DomainList `db:"list"`
+	CreatedAt time.Time  `db:"created_at"`
+}
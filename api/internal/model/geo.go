@@ -0,0 +1,48 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// GeoRule sends visitors from any of Countries, given as ISO 3166-1 alpha-2
+// codes, to TargetURL instead of the link's default target.
+type GeoRule struct {
+	Countries []string `json:"countries"`
+	TargetURL string   `json:"target_url"`
+}
+
+// GeoRules are evaluated in order and the first match wins. They are stored
+// as a JSON column; an empty list is stored as NULL.
+type GeoRules []GeoRule
+
+// Match returns the target of the first rule listing country, or false when
+// none does or the country is unknown.
+func (r GeoRules) Match(country string) (string, bool) {
+	if country == "" {
+		return "", false
+	}
+	for _, rule := range r {
+		for _, c := range rule.Countries {
+			if c == country {
+				return rule.TargetURL, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (r GeoRules) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *GeoRules) Scan(src any) error {
+	if src == nil {
+		*r = nil
+		return nil
+	}
+	return scanJSON(src, r, "geo rules")
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// scanJSON decodes a JSON column into dst for the sql.Scanner implementations
+// of this package. what names the column in errors.
+func scanJSON(src, dst any, what string) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("scanning %s: unsupported type %T", what, src)
+	}
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// ReportReason classifies an abuse report.
+type ReportReason string
+
+const (
+	ReportPhishing ReportReason = "phishing"
+	ReportMalware  ReportReason = "malware"
+	ReportSpam     ReportReason = "spam"
+	ReportIllegal  ReportReason = "illegal"
+	ReportOther    ReportReason = "other"
+)
+
+// ValidReportReasons lists the reasons a report may give.
+var ValidReportReasons = map[ReportReason]bool{
+	ReportPhishing: true,
+	ReportMalware:  true,
+	ReportSpam:     true,
+	ReportIllegal:  true,
+	ReportOther:    true,
+}
+
+// AbuseReport is a row of the abuse_reports table. Details is nil when the
+// reporter gave none. ReporterID is a keyed hash of the reporter's IP.
+type AbuseReport struct {
+	ID         uint64       `db:"id"`
+	URLID      uint64       `db:"url_id"`
+	Slug       string       `db:"slug"`
+	Reason     ReportReason `db:"reason"`
+	Details    *string      `db:"details"`
+	ReporterID string       `db:"reporter_id"`
+	CreatedAt  time.Time    `db:"created_at"`
+}
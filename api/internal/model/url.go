@@ -2,50 +2,175 @@ package model
 
 import "time"
 
+// TTL is a link lifetime as sent by clients: a Go duration string such as
+// "90m" or "36h", or TTLNever for a link that does not expire.
 type TTL string
 
+// TTLNever asks for a link that does not expire.
+const TTLNever TTL = "never"
+
+// ValidRedirectStatuses lists the HTTP statuses a link may redirect with.
+var ValidRedirectStatuses = map[int]bool{
+	301: true,
+	302: true,
+	307: true,
+	308: true,
+}
+
+// QueryPassthrough forwards a visit's query string to the target. Its value
+// names the side whose value is kept when both carry the same parameter.
+type QueryPassthrough string
+
 const (
-	TTL1Hour  TTL = "1h"
-	TTL1Day   TTL = "24h"
-	TTL1Week  TTL = "168h"
-	TTL1Month TTL = "720h"
-	TTL1Year  TTL = "8760h"
+	QueryTargetWins  QueryPassthrough = "target"
+	QueryRequestWins QueryPassthrough = "request"
 )
 
-var ValidTTLs = map[TTL]time.Duration{
-	TTL1Hour:  time.Hour,
-	TTL1Day:   24 * time.Hour,
-	TTL1Week:  7 * 24 * time.Hour,
-	TTL1Month: 30 * 24 * time.Hour,
-	TTL1Year:  365 * 24 * time.Hour,
+// PermanentRedirect reports whether status lets browsers keep the redirect
+// without asking the server again.
+func PermanentRedirect(status int) bool {
+	return status == 301 || status == 308
 }
 
+// URL is a row of the urls table.
 type URL struct {
-	ID              uint64    `db:"id"`
-	Slug            string    `db:"slug"`
-	TargetURL       string    `db:"target_url"`
-	PasswordHash    *string   `db:"password_hash"`
-	ManageTokenHash string    `db:"manage_token_hash"`
-	ExpiresAt       time.Time `db:"expires_at"`
-	CreatedAt       time.Time `db:"created_at"`
+	ID              uint64  `db:"id"`
+	Slug            string  `db:"slug"`
+	TargetURL       string  `db:"target_url"`
+	PasswordHash    *string `db:"password_hash"`
+	ManageTokenHash string  `db:"manage_token_hash"`
+	// MaxClicks and ClicksRemaining are nil for links that accept unlimited
+	// visits.
+	MaxClicks       *int64 `db:"max_clicks"`
+	ClicksRemaining *int64 `db:"clicks_remaining"`
+	// ActivatesAt is nil for links that redirect as soon as they are created.
+	ActivatesAt *time.Time `db:"activates_at"`
+	// SlidingTTLSeconds is how far each visit pushes ExpiresAt forward, nil
+	// for a fixed expiration.
+	SlidingTTLSeconds *int64 `db:"sliding_ttl_seconds"`
+	// RedirectStatus is nil for links that follow the server's default.
+	RedirectStatus *int `db:"redirect_status"`
+	// QueryPassthrough is nil for links that drop the visit's query string.
+	QueryPassthrough *QueryPassthrough `db:"query_passthrough"`
+	PrefixMatch      bool              `db:"prefix_match"`
+	// UTM is nil for links that follow the server's default campaign
+	// parameters.
+	UTM *UTM `db:"utm"`
+	// DeviceRules and GeoRules override TargetURL for visitors on matching
+	// platforms or countries; Variants replace it for everyone else.
+	DeviceRules    DeviceRules `db:"device_rules"`
+	GeoRules       GeoRules    `db:"geo_rules"`
+	Variants       Variants    `db:"variants"`
+	StickyVariants bool        `db:"sticky_variants"`
+	// DisabledAt is set once a link has been taken down, with DisabledReason
+	// saying why.
+	DisabledAt     *time.Time `db:"disabled_at"`
+	DisabledReason *string    `db:"disabled_reason"`
+	// CreatorID is a keyed hash of the creator's IP, nil for links created
+	// before it was recorded.
+	CreatorID *string `db:"creator_id"`
+	// ExpiresAt is nil for links that never expire.
+	ExpiresAt *time.Time `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
 }
 
 // CachedURL is the payload stored in Redis. It contains everything needed
-// to serve a redirect or password gate without hitting MySQL.
+// to serve a redirect, password gate or preview without hitting MySQL.
 type CachedURL struct {
-	TargetURL    string `json:"target_url"`
+	// TargetURL is empty in the placeholders of disabled and not yet active
+	// links, so their target never sits in Redis.
+	TargetURL    string `json:"target_url,omitempty"`
 	Protected    bool   `json:"protected"`
 	PasswordHash string `json:"password_hash,omitempty"`
+	// SealedPasswordHash replaces PasswordHash in Redis when the cache
+	// encrypts password hashes.
+	SealedPasswordHash string `json:"sealed_password_hash,omitempty"`
+	// LimitedClicks marks links whose visits must be counted before the
+	// target is revealed.
+	LimitedClicks bool `json:"limited_clicks,omitempty"`
+	// SlidingTTLSeconds marks links whose expiration each visit extends.
+	SlidingTTLSeconds int64 `json:"sliding_ttl,omitempty"`
+	// RedirectStatus is zero when the server default applies.
+	RedirectStatus   int              `json:"redirect_status,omitempty"`
+	QueryPassthrough QueryPassthrough `json:"query_passthrough,omitempty"`
+	PrefixMatch      bool             `json:"prefix_match,omitempty"`
+	UTM              *UTM             `json:"utm,omitempty"`
+	DeviceRules      DeviceRules      `json:"device_rules,omitempty"`
+	GeoRules         GeoRules         `json:"geo_rules,omitempty"`
+	Variants         Variants         `json:"variants,omitempty"`
+	StickyVariants   bool             `json:"sticky_variants,omitempty"`
+	// ActivatesAt is only set in the placeholder of a link that is not
+	// active yet.
+	ActivatesAt *time.Time `json:"activates_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// Disabled marks the placeholder of a link that has been taken down.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Pending reports whether c is the placeholder of a link that is not active
+// at now.
+func (c *CachedURL) Pending(now time.Time) bool {
+	return c.ActivatesAt != nil && now.Before(*c.ActivatesAt)
+}
+
+// VariesByVisitor reports whether visitors of the same URL may be sent to
+// different destinations, so shared caches must not store the redirect.
+func (c *CachedURL) VariesByVisitor() bool {
+	return len(c.DeviceRules) > 0 || len(c.GeoRules) > 0 || len(c.Variants) > 0
+}
+
+// MustReachServer reports whether every visit has to be answered by the
+// server: protected links check the visitor's token, and visits to
+// click-limited, sliding and A/B links are counted.
+func (c *CachedURL) MustReachServer() bool {
+	return c.Protected || c.LimitedClicks || c.SlidingTTLSeconds > 0 || len(c.Variants) > 0
 }
 
-// ToCached projects a URL into the Redis cache payload.
-func (u *URL) ToCached() *CachedURL {
+// Active reports whether u redirects at now.
+func (u *URL) Active(now time.Time) bool {
+	return u.ActivatesAt == nil || !now.Before(*u.ActivatesAt)
+}
+
+// ToCached projects a URL into the Redis cache payload as of now.
+func (u *URL) ToCached(now time.Time) *CachedURL {
+	if u.DisabledAt != nil {
+		return &CachedURL{Disabled: true, ExpiresAt: u.ExpiresAt}
+	}
+	if !u.Active(now) {
+		return &CachedURL{ActivatesAt: u.ActivatesAt, ExpiresAt: u.ExpiresAt}
+	}
 	cached := &CachedURL{
-		TargetURL: u.TargetURL,
-		Protected: u.PasswordHash != nil,
+		TargetURL:      u.TargetURL,
+		Protected:      u.PasswordHash != nil,
+		LimitedClicks:  u.MaxClicks != nil,
+		PrefixMatch:    u.PrefixMatch,
+		UTM:            u.UTM,
+		DeviceRules:    u.DeviceRules,
+		GeoRules:       u.GeoRules,
+		Variants:       u.Variants,
+		StickyVariants: u.StickyVariants,
+		ExpiresAt:      u.ExpiresAt,
 	}
 	if u.PasswordHash != nil {
 		cached.PasswordHash = *u.PasswordHash
 	}
+	if u.SlidingTTLSeconds != nil {
+		cached.SlidingTTLSeconds = *u.SlidingTTLSeconds
+	}
+	if u.RedirectStatus != nil {
+		cached.RedirectStatus = *u.RedirectStatus
+	}
+	if u.QueryPassthrough != nil {
+		cached.QueryPassthrough = *u.QueryPassthrough
+	}
 	return cached
 }
+
+// SlidingTTL returns how far each visit pushes the expiration forward, or zero
+// for links with a fixed expiration.
+func (u *URL) SlidingTTL() time.Duration {
+	if u.SlidingTTLSeconds == nil {
+		return 0
+	}
+	return time.Duration(*u.SlidingTTLSeconds) * time.Second
+}
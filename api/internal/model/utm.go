@@ -0,0 +1,54 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/url"
+)
+
+// UTM holds the campaign parameters merged into a link's target at redirect
+// time. Empty fields are left out. It is stored as a JSON column.
+type UTM struct {
+	Source   string `json:"utm_source,omitempty"`
+	Medium   string `json:"utm_medium,omitempty"`
+	Campaign string `json:"utm_campaign,omitempty"`
+	Term     string `json:"utm_term,omitempty"`
+	Content  string `json:"utm_content,omitempty"`
+}
+
+// Params returns the non-empty parameters keyed by their query names.
+func (u UTM) Params() map[string]string {
+	params := make(map[string]string, 5)
+	for key, value := range map[string]string{
+		"utm_source":   u.Source,
+		"utm_medium":   u.Medium,
+		"utm_campaign": u.Campaign,
+		"utm_term":     u.Term,
+		"utm_content":  u.Content,
+	} {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+// Missing returns the parameters query does not carry yet. Parameters query
+// already carries are never overwritten.
+func (u UTM) Missing(query url.Values) url.Values {
+	missing := make(url.Values)
+	for key, value := range u.Params() {
+		if _, taken := query[key]; !taken {
+			missing.Set(key, value)
+		}
+	}
+	return missing
+}
+
+func (u UTM) Value() (driver.Value, error) {
+	return json.Marshal(u)
+}
+
+func (u *UTM) Scan(src any) error {
+	return scanJSON(src, u, "utm")
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Variant is one destination of an A/B link. Each visit picks a variant with
+// probability Weight over the sum of all weights.
+type Variant struct {
+	Name      string `json:"name"`
+	TargetURL string `json:"target_url"`
+	Weight    int    `json:"weight"`
+}
+
+// Variants are stored as a JSON column; an empty list is stored as NULL.
+type Variants []Variant
+
+// TotalWeight returns the sum of all weights.
+func (v Variants) TotalWeight() int {
+	total := 0
+	for _, variant := range v {
+		total += variant.Weight
+	}
+	return total
+}
+
+// Pick returns the variant owning point n of the cumulative weight range, so
+// a uniform n in [0, TotalWeight()) picks each variant by its weight.
+func (v Variants) Pick(n int) Variant {
+	for _, variant := range v {
+		if n < variant.Weight {
+			return variant
+		}
+		n -= variant.Weight
+	}
+	return v[len(v)-1]
+}
+
+// Find returns the variant called name.
+func (v Variants) Find(name string) (Variant, bool) {
+	for _, variant := range v {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return Variant{}, false
+}
+
+func (v Variants) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+func (v *Variants) Scan(src any) error {
+	if src == nil {
+		*v = nil
+		return nil
+	}
+	return scanJSON(src, v, "variants")
+}
+
+// Click is one revealed target, recorded for the owner's analytics. Variant
+// is empty for links without variants.
+type Click struct {
+	Slug      string
+	Variant   string
+	ClickedAt time.Time
+}
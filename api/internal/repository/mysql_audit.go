@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"encurtador/internal/model"
+)
+
+type mysqlAuditLog struct {
+	db *sqlx.DB
+}
+
+func NewMySQLAuditLog(db *sqlx.DB) AuditLog {
+	return &mysqlAuditLog{db: db}
+}
+
+func (l *mysqlAuditLog) Append(ctx context.Context, entry *model.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (url_id, slug, action, actor, actor_id, state_before, state_after)
+		VALUES (:url_id, :slug, :action, :actor, :actor_id, :state_before, :state_after)`
+	if _, err := l.db.NamedExecContext(ctx, query, entry); err != nil {
+		return fmt.Errorf("appending audit entry: %w", err)
+	}
+	return nil
+}
+
+func (l *mysqlAuditLog) List(ctx context.Context, urlID, beforeID uint64, limit int) ([]model.AuditEntry, error) {
+	query := `
+		SELECT id, url_id, slug, action, actor, actor_id, state_before, state_after, created_at
+		FROM audit_log
+		WHERE url_id = ? AND action NOT IN (?, ?) AND (? = 0 OR id < ?)
+		ORDER BY id DESC
+		LIMIT ?`
+	var entries []model.AuditEntry
+	if err := l.db.SelectContext(ctx, &entries, query, urlID, model.AuditView, model.AuditViewReports, beforeID, beforeID, limit); err != nil {
+		return nil, fmt.Errorf("listing audit entries: %w", err)
+	}
+	return entries, nil
+}
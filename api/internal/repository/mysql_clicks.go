@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"encurtador/internal/model"
+)
+
+type mysqlClickLog struct {
+	db *sqlx.DB
+}
+
+func NewMySQLClickLog(db *sqlx.DB) ClickLog {
+	return &mysqlClickLog{db: db}
+}
+
+// Record stores click against the link currently holding its slug. Clicks on
+// links removed in the meantime are silently dropped.
+func (l *mysqlClickLog) Record(ctx context.Context, click model.Click) error {
+	variant := sql.NullString{String: click.Variant, Valid: click.Variant != ""}
+	_, err := l.db.ExecContext(ctx, `
+		INSERT INTO link_clicks (url_id, variant, clicked_at)
+		SELECT id, ?, ? FROM urls WHERE slug = ?`,
+		variant, click.ClickedAt, click.Slug)
+	if err != nil {
+		return fmt.Errorf("recording click: %w", err)
+	}
+	return nil
+}
+
+func (l *mysqlClickLog) Counts(ctx context.Context, urlID uint64) (int64, map[string]int64, error) {
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT variant, COUNT(*) FROM link_clicks WHERE url_id = ? GROUP BY variant`, urlID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("counting clicks: %w", err)
+	}
+	defer rows.Close()
+
+	var total int64
+	var byVariant map[string]int64
+	for rows.Next() {
+		var (
+			variant sql.NullString
+			n       int64
+		)
+		if err := rows.Scan(&variant, &n); err != nil {
+			return 0, nil, fmt.Errorf("scanning click count: %w", err)
+		}
+		total += n
+		if variant.Valid {
+			if byVariant == nil {
+				byVariant = make(map[string]int64)
+			}
+			byVariant[variant.String] = n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("counting clicks: %w", err)
+	}
+	return total, byVariant, nil
+}
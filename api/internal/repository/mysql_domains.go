@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"encurtador/internal/model"
+)
+
+type mysqlDomainRules struct {
+	db *sqlx.DB
+}
+
+func NewMySQLDomainRules(db *sqlx.DB) DomainRuleRepository {
+	return &mysqlDomainRules{db: db}
+}
+
+func (r *mysqlDomainRules) List(ctx context.Context) ([]model.DomainRule, error) {
+	var rules []model.DomainRule
+	if err := r.db.SelectContext(ctx, &rules, `
+		SELECT id, pattern, list, created_at FROM domain_rules ORDER BY id`); err != nil {
+		return nil, fmt.Errorf("listing domain rules: %w", err)
+	}
+	return rules, nil
+}
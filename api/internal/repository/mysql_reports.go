@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"encurtador/internal/model"
+)
+
+type mysqlAbuseReports struct {
+	db *sqlx.DB
+}
+
+func NewMySQLAbuseReports(db *sqlx.DB) AbuseReportRepository {
+	return &mysqlAbuseReports{db: db}
+}
+
+// Create stores report against the unexpired link holding report.Slug.
+// Returns false when there is no such link.
+func (r *mysqlAbuseReports) Create(ctx context.Context, report *model.AbuseReport) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO abuse_reports (url_id, reason, details, reporter_id)
+		SELECT id, ?, ?, ? FROM urls WHERE slug = ? AND `+notExpired,
+		report.Reason, report.Details, report.ReporterID, report.Slug)
+	if err != nil {
+		return false, fmt.Errorf("inserting abuse report: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+func (r *mysqlAbuseReports) List(ctx context.Context, slug string, beforeID uint64, limit int) ([]model.AbuseReport, error) {
+	query := `
+		SELECT r.id, r.url_id, u.slug, r.reason, r.details, r.reporter_id, r.created_at
+		FROM abuse_reports r
+		JOIN urls u ON u.id = r.url_id
+		WHERE (? = 0 OR r.id < ?) AND (? = '' OR u.slug = ?)
+		ORDER BY r.id DESC
+		LIMIT ?`
+	var reports []model.AbuseReport
+	if err := r.db.SelectContext(ctx, &reports, query, beforeID, beforeID, slug, slug, limit); err != nil {
+		return nil, fmt.Errorf("listing abuse reports: %w", err)
+	}
+	return reports, nil
+}
+
+func (r *mysqlAbuseReports) Count(ctx context.Context, urlID uint64) (int64, error) {
+	var n int64
+	if err := r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM abuse_reports WHERE url_id = ?`, urlID); err != nil {
+		return 0, fmt.Errorf("counting abuse reports: %w", err)
+	}
+	return n, nil
+}
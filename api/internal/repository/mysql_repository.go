@@ -5,12 +5,22 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 
 	"encurtador/internal/model"
 )
 
+// urlColumns lists the columns scanned into model.URL by every SELECT.
+const urlColumns = `id, slug, target_url, password_hash, manage_token_hash, max_clicks, clicks_remaining, activates_at, sliding_ttl_seconds, redirect_status, query_passthrough, prefix_match, utm, device_rules, geo_rules, variants, sticky_variants, disabled_at, disabled_reason, creator_id, expires_at, created_at`
+
+// notExpired matches rows that have not expired; a NULL expires_at never expires.
+const notExpired = `(expires_at IS NULL OR expires_at > NOW())`
+
+// servable matches rows that still redirect: not expired and not disabled.
+const servable = notExpired + ` AND disabled_at IS NULL`
+
 type mysqlURLRepository struct {
 	db *sqlx.DB
 }
@@ -21,8 +31,8 @@ func NewMySQLURLRepository(db *sqlx.DB) URLRepository {
 
 func (r *mysqlURLRepository) Create(ctx context.Context, url *model.URL) error {
 	query := `
-		INSERT INTO urls (slug, target_url, password_hash, manage_token_hash, expires_at)
-		VALUES (:slug, :target_url, :password_hash, :manage_token_hash, :expires_at)`
+		INSERT INTO urls (slug, target_url, password_hash, manage_token_hash, max_clicks, clicks_remaining, activates_at, sliding_ttl_seconds, redirect_status, query_passthrough, prefix_match, utm, device_rules, geo_rules, variants, sticky_variants, creator_id, expires_at)
+		VALUES (:slug, :target_url, :password_hash, :manage_token_hash, :max_clicks, :clicks_remaining, :activates_at, :sliding_ttl_seconds, :redirect_status, :query_passthrough, :prefix_match, :utm, :device_rules, :geo_rules, :variants, :sticky_variants, :creator_id, :expires_at)`
 	if _, err := r.db.NamedExecContext(ctx, query, url); err != nil {
 		return fmt.Errorf("inserting url: %w", err)
 	}
@@ -32,9 +42,9 @@ func (r *mysqlURLRepository) Create(ctx context.Context, url *model.URL) error {
 func (r *mysqlURLRepository) FindBySlug(ctx context.Context, slug string) (*model.URL, error) {
 	var url model.URL
 	query := `
-		SELECT id, slug, target_url, password_hash, manage_token_hash, expires_at, created_at
+		SELECT ` + urlColumns + `
 		FROM urls
-		WHERE slug = ? AND expires_at > NOW()`
+		WHERE slug = ? AND ` + notExpired
 	err := r.db.GetContext(ctx, &url, query, slug)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -45,6 +55,34 @@ func (r *mysqlURLRepository) FindBySlug(ctx context.Context, slug string) (*mode
 	return &url, nil
 }
 
+func (r *mysqlURLRepository) FindByManageToken(ctx context.Context, slug, manageTokenHash string) (*model.URL, error) {
+	var url model.URL
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE slug = ? AND manage_token_hash = ? AND ` + notExpired
+	err := r.db.GetContext(ctx, &url, query, slug, manageTokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding url by manage token: %w", err)
+	}
+	return &url, nil
+}
+
+func (r *mysqlURLRepository) FindAny(ctx context.Context, slug string) (*model.URL, error) {
+	var url model.URL
+	err := r.db.GetContext(ctx, &url, `SELECT `+urlColumns+` FROM urls WHERE slug = ?`, slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding url: %w", err)
+	}
+	return &url, nil
+}
+
 func (r *mysqlURLRepository) SlugExists(ctx context.Context, slug string) (bool, error) {
 	var exists bool
 	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM urls WHERE slug = ?)`, slug).Scan(&exists)
@@ -54,9 +92,22 @@ func (r *mysqlURLRepository) SlugExists(ctx context.Context, slug string) (bool,
 	return exists, nil
 }
 
+// Update writes the owner-editable fields of url back to its row.
+func (r *mysqlURLRepository) Update(ctx context.Context, url *model.URL) error {
+	query := `
+		UPDATE urls
+		SET target_url = :target_url, utm = :utm, device_rules = :device_rules, geo_rules = :geo_rules,
+		    variants = :variants, sticky_variants = :sticky_variants
+		WHERE id = :id`
+	if _, err := r.db.NamedExecContext(ctx, query, url); err != nil {
+		return fmt.Errorf("updating url: %w", err)
+	}
+	return nil
+}
+
 func (r *mysqlURLRepository) ExpireBySlug(ctx context.Context, slug, manageTokenHash string) (bool, error) {
 	result, err := r.db.ExecContext(ctx,
-		`UPDATE urls SET expires_at = NOW() WHERE slug = ? AND manage_token_hash = ? AND expires_at > NOW()`,
+		`UPDATE urls SET expires_at = NOW() WHERE slug = ? AND manage_token_hash = ? AND `+notExpired,
 		slug, manageTokenHash)
 	if err != nil {
 		return false, fmt.Errorf("expiring url: %w", err)
@@ -65,6 +116,123 @@ func (r *mysqlURLRepository) ExpireBySlug(ctx context.Context, slug, manageToken
 	return rows > 0, nil
 }
 
+func (r *mysqlURLRepository) SetPassword(ctx context.Context, id uint64, hash *string) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE urls SET password_hash = ? WHERE id = ?`, hash, id); err != nil {
+		return fmt.Errorf("setting url password: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlURLRepository) ReplacePasswordHash(ctx context.Context, slug, oldHash, newHash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET password_hash = ? WHERE slug = ? AND password_hash = ?`, newHash, slug, oldHash)
+	if err != nil {
+		return false, fmt.Errorf("replacing password hash: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+func (r *mysqlURLRepository) RotateManageToken(ctx context.Context, slug, oldHash, newHash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET manage_token_hash = ? WHERE slug = ? AND manage_token_hash = ? AND `+notExpired,
+		newHash, slug, oldHash)
+	if err != nil {
+		return false, fmt.Errorf("rotating manage token: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ConsumeClick atomically takes one visit from a click-limited link. The new
+// count is captured with LAST_INSERT_ID(expr) so no second query can race, and
+// the link is expired in the same statement when it reaches zero (MySQL applies
+// single-table SET assignments left to right). Returns false when the link has
+// no visits left, has expired, or is not click-limited.
+func (r *mysqlURLRepository) ConsumeClick(ctx context.Context, slug string) (bool, int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE urls
+		SET clicks_remaining = LAST_INSERT_ID(clicks_remaining - 1),
+		    expires_at = IF(clicks_remaining = 0, NOW(), expires_at)
+		WHERE slug = ? AND clicks_remaining > 0 AND `+servable, slug)
+	if err != nil {
+		return false, 0, fmt.Errorf("consuming click: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return false, 0, nil
+	}
+	remaining, err := result.LastInsertId()
+	if err != nil {
+		return false, 0, fmt.Errorf("reading remaining clicks: %w", err)
+	}
+	return true, remaining, nil
+}
+
+// ExtendExpiry moves a sliding link's expiration forward to until. It never
+// shortens a link, revives an expired one, or touches fixed-expiry links.
+func (r *mysqlURLRepository) ExtendExpiry(ctx context.Context, slug string, until time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE urls SET expires_at = ?
+		WHERE slug = ? AND sliding_ttl_seconds IS NOT NULL AND expires_at < ? AND `+servable,
+		until, slug, until)
+	if err != nil {
+		return false, fmt.Errorf("extending url expiry: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ListServable returns up to limit links that still redirect, in id order,
+// starting after afterID, for jobs that walk every live link.
+func (r *mysqlURLRepository) ListServable(ctx context.Context, afterID uint64, limit int) ([]model.URL, error) {
+	var urls []model.URL
+	query := `
+		SELECT ` + urlColumns + `
+		FROM urls
+		WHERE id > ? AND ` + servable + `
+		ORDER BY id
+		LIMIT ?`
+	if err := r.db.SelectContext(ctx, &urls, query, afterID, limit); err != nil {
+		return nil, fmt.Errorf("listing urls: %w", err)
+	}
+	return urls, nil
+}
+
+// Disable takes a link down, recording why. Returns false when the link is
+// already disabled.
+func (r *mysqlURLRepository) Disable(ctx context.Context, id uint64, reason string) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET disabled_at = NOW(), disabled_reason = ? WHERE id = ? AND disabled_at IS NULL`,
+		reason, id)
+	if err != nil {
+		return false, fmt.Errorf("disabling url: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// Enable lifts a link's disabled state. Returns false when it is not disabled.
+func (r *mysqlURLRepository) Enable(ctx context.Context, id uint64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET disabled_at = NULL, disabled_reason = NULL WHERE id = ? AND disabled_at IS NOT NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("enabling url: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+func (r *mysqlURLRepository) ForceExpire(ctx context.Context, id uint64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET expires_at = NOW() WHERE id = ? AND `+notExpired, id)
+	if err != nil {
+		return false, fmt.Errorf("expiring url: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
 func (r *mysqlURLRepository) DeleteExpired(ctx context.Context) error {
 	if _, err := r.db.ExecContext(ctx, `DELETE FROM urls WHERE expires_at < NOW()`); err != nil {
 		return fmt.Errorf("deleting expired urls: %w", err)
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// failureRetention bounds how long the per-slug total failure counter is kept
+// after the last failed attempt, so counters of deleted links eventually go away.
+const failureRetention = 30 * 24 * time.Hour
+
+type redisAttemptTracker struct {
+	client *redis.Client
+}
+
+func NewRedisAttemptTracker(client *redis.Client) AttemptTracker {
+	return &redisAttemptTracker{client: client}
+}
+
+func consecutiveFailKey(slug string) string {
+	return "unlock:fail:" + slug
+}
+
+func totalFailKey(slug string) string {
+	return "unlock:total:" + slug
+}
+
+func lockKey(slug string) string {
+	return "unlock:lock:" + slug
+}
+
+func (t *redisAttemptTracker) RecordFailure(ctx context.Context, slug string, window time.Duration) (int64, error) {
+	pipe := t.client.TxPipeline()
+	consecutive := pipe.Incr(ctx, consecutiveFailKey(slug))
+	pipe.Expire(ctx, consecutiveFailKey(slug), window)
+	pipe.Incr(ctx, totalFailKey(slug))
+	pipe.Expire(ctx, totalFailKey(slug), failureRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("recording failed attempt: %w", err)
+	}
+	return consecutive.Val(), nil
+}
+
+func (t *redisAttemptTracker) Lock(ctx context.Context, slug string, d time.Duration) error {
+	if err := t.client.Set(ctx, lockKey(slug), 1, d).Err(); err != nil {
+		return fmt.Errorf("locking slug: %w", err)
+	}
+	return nil
+}
+
+func (t *redisAttemptTracker) LockedFor(ctx context.Context, slug string) (time.Duration, error) {
+	ttl, err := t.client.PTTL(ctx, lockKey(slug)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("reading lock ttl: %w", err)
+	}
+	// PTTL reports -2 for a missing key and -1 for a key without expiry;
+	// neither is an active lock.
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (t *redisAttemptTracker) TotalFailures(ctx context.Context, slug string) (int64, error) {
+	n, err := t.client.Get(ctx, totalFailKey(slug)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading failure count: %w", err)
+	}
+	return n, nil
+}
+
+func (t *redisAttemptTracker) ResetConsecutive(ctx context.Context, slug string) error {
+	if err := t.client.Del(ctx, consecutiveFailKey(slug)).Err(); err != nil {
+		return fmt.Errorf("resetting failed attempts: %w", err)
+	}
+	return nil
+}
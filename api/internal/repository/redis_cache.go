@@ -57,3 +57,15 @@ func (c *redisURLCache) Delete(ctx context.Context, slug string) error {
 	}
 	return nil
 }
+
+func slideKey(slug string) string {
+	return "slide:" + slug
+}
+
+func (c *redisURLCache) ClaimSlide(ctx context.Context, slug string, interval time.Duration) (bool, error) {
+	ok, err := c.client.SetNX(ctx, slideKey(slug), 1, interval).Result()
+	if err != nil {
+		return false, fmt.Errorf("claiming slide: %w", err)
+	}
+	return ok, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// decrementIfExists only decrements an existing counter, so a missing key is
+// reported instead of being created at -1.
+var decrementIfExists = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+  return redis.call("DECR", KEYS[1])
+end
+return false
+`)
+
+type redisClickCounter struct {
+	client *redis.Client
+}
+
+func NewRedisClickCounter(client *redis.Client) ClickCounter {
+	return &redisClickCounter{client: client}
+}
+
+func clicksKey(slug string) string {
+	return "clicks:" + slug
+}
+
+func (c *redisClickCounter) Decrement(ctx context.Context, slug string) (int64, bool, error) {
+	n, err := decrementIfExists.Run(ctx, c.client, []string{clicksKey(slug)}).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("decrementing click counter: %w", err)
+	}
+	return n, true, nil
+}
+
+func (c *redisClickCounter) Restore(ctx context.Context, slug string) error {
+	if err := c.client.Incr(ctx, clicksKey(slug)).Err(); err != nil {
+		return fmt.Errorf("restoring click counter: %w", err)
+	}
+	return nil
+}
+
+func (c *redisClickCounter) Seed(ctx context.Context, slug string, remaining int64, ttl time.Duration) error {
+	if err := c.client.SetNX(ctx, clicksKey(slug), remaining, ttl).Err(); err != nil {
+		return fmt.Errorf("seeding click counter: %w", err)
+	}
+	return nil
+}
+
+func (c *redisClickCounter) Delete(ctx context.Context, slug string) error {
+	if err := c.client.Del(ctx, clicksKey(slug)).Err(); err != nil {
+		return fmt.Errorf("deleting click counter: %w", err)
+	}
+	return nil
+}
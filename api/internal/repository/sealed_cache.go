@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"encurtador/internal/model"
+)
+
+// sealedURLCache keeps password hashes out of the underlying cache: they are
+// stored encrypted with AES-256-GCM under a key Redis never sees, with the
+// slug as additional data so a sealed hash cannot be moved to another link.
+type sealedURLCache struct {
+	URLCache
+	aead cipher.AEAD
+}
+
+// NewSealedURLCache wraps cache so that the password hashes it stores are
+// encrypted with a key derived from secret. Entries that cannot be opened,
+// such as plain ones written before sealing was enabled or ones sealed under
+// another secret, read as misses and are replaced from MySQL.
+func NewSealedURLCache(cache URLCache, secret []byte) (URLCache, error) {
+	key := sha256.Sum256(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("creating cache cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating cache cipher: %w", err)
+	}
+	return &sealedURLCache{URLCache: cache, aead: aead}, nil
+}
+
+func (c *sealedURLCache) Get(ctx context.Context, slug string) (*model.CachedURL, error) {
+	cached, err := c.URLCache.Get(ctx, slug)
+	if err != nil || cached == nil || !cached.Protected {
+		return cached, err
+	}
+	hash, err := c.open(slug, cached.SealedPasswordHash)
+	if err != nil {
+		slog.Warn("discarding cached url with unreadable password hash", "slug", slug, "error", err)
+		return nil, nil
+	}
+	cached.PasswordHash, cached.SealedPasswordHash = hash, ""
+	return cached, nil
+}
+
+func (c *sealedURLCache) Set(ctx context.Context, slug string, cached *model.CachedURL, ttl time.Duration) error {
+	if cached.PasswordHash != "" {
+		sealed := *cached
+		sealed.PasswordHash, sealed.SealedPasswordHash = "", c.seal(slug, cached.PasswordHash)
+		cached = &sealed
+	}
+	return c.URLCache.Set(ctx, slug, cached, ttl)
+}
+
+// seal returns base64(nonce || ciphertext) of hash.
+func (c *sealedURLCache) seal(slug, hash string) string {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(hash)+c.aead.Overhead())
+	// rand.Read never fails on supported platforms.
+	_, _ = rand.Read(nonce)
+	return base64.RawStdEncoding.EncodeToString(c.aead.Seal(nonce, nonce, []byte(hash), []byte(slug)))
+}
+
+func (c *sealedURLCache) open(slug, sealed string) (string, error) {
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("decoding sealed password hash: %w", err)
+	}
+	if len(data) < c.aead.NonceSize() {
+		return "", fmt.Errorf("sealed password hash too short")
+	}
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	hash, err := c.aead.Open(nil, nonce, ciphertext, []byte(slug))
+	if err != nil {
+		return "", fmt.Errorf("opening sealed password hash: %w", err)
+	}
+	return string(hash), nil
+}
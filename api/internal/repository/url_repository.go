@@ -10,8 +10,28 @@ import (
 type URLRepository interface {
 	Create(ctx context.Context, url *model.URL) error
 	FindBySlug(ctx context.Context, slug string) (*model.URL, error)
+	FindByManageToken(ctx context.Context, slug, manageTokenHash string) (*model.URL, error)
+	// FindAny returns the link holding slug whatever its state, including
+	// expired links not yet cleaned up.
+	FindAny(ctx context.Context, slug string) (*model.URL, error)
 	SlugExists(ctx context.Context, slug string) (bool, error)
+	Update(ctx context.Context, url *model.URL) error
 	ExpireBySlug(ctx context.Context, slug, manageTokenHash string) (bool, error)
+	// SetPassword replaces a link's password hash; nil removes the password.
+	SetPassword(ctx context.Context, id uint64, hash *string) error
+	// ReplacePasswordHash swaps a link's password hash for an equivalent one,
+	// provided it is still oldHash.
+	ReplacePasswordHash(ctx context.Context, slug, oldHash, newHash string) (bool, error)
+	// RotateManageToken swaps the manage token hash of a live link, provided
+	// it still matches oldHash.
+	RotateManageToken(ctx context.Context, slug, oldHash, newHash string) (bool, error)
+	ConsumeClick(ctx context.Context, slug string) (consumed bool, remaining int64, err error)
+	ExtendExpiry(ctx context.Context, slug string, until time.Time) (bool, error)
+	ListServable(ctx context.Context, afterID uint64, limit int) ([]model.URL, error)
+	Disable(ctx context.Context, id uint64, reason string) (bool, error)
+	Enable(ctx context.Context, id uint64) (bool, error)
+	// ForceExpire expires a link without its manage token.
+	ForceExpire(ctx context.Context, id uint64) (bool, error)
 	DeleteExpired(ctx context.Context) error
 }
 
@@ -19,4 +39,66 @@ type URLCache interface {
 	Get(ctx context.Context, slug string) (*model.CachedURL, error)
 	Set(ctx context.Context, slug string, cached *model.CachedURL, ttl time.Duration) error
 	Delete(ctx context.Context, slug string) error
+	// ClaimSlide reports whether the caller may extend a sliding link now,
+	// granting the claim to at most one caller per interval.
+	ClaimSlide(ctx context.Context, slug string, interval time.Duration) (bool, error)
+}
+
+// ClickLog stores one row per revealed target for the owner's analytics.
+type ClickLog interface {
+	Record(ctx context.Context, click model.Click) error
+	// Counts returns the total number of clicks on a link and, for A/B links,
+	// the number per variant.
+	Counts(ctx context.Context, urlID uint64) (total int64, byVariant map[string]int64, err error)
+}
+
+// DomainRuleRepository reads the operator's destination blocklist and
+// allowlist.
+type DomainRuleRepository interface {
+	List(ctx context.Context) ([]model.DomainRule, error)
+}
+
+// AbuseReportRepository stores the public's reports of abusive links.
+type AbuseReportRepository interface {
+	Create(ctx context.Context, report *model.AbuseReport) (bool, error)
+	// List returns up to limit reports, newest first, older than beforeID
+	// when it is not zero, and only for slug when it is not empty.
+	List(ctx context.Context, slug string, beforeID uint64, limit int) ([]model.AbuseReport, error)
+	Count(ctx context.Context, urlID uint64) (int64, error)
+}
+
+// AuditLog appends entries to the append-only record of link actions.
+type AuditLog interface {
+	Append(ctx context.Context, entry *model.AuditEntry) error
+	// List returns up to limit entries of a link, newest first, older than
+	// beforeID when it is not zero. Operator lookups are left out: they are
+	// for operators' accountability, not part of the link's history.
+	List(ctx context.Context, urlID, beforeID uint64, limit int) ([]model.AuditEntry, error)
+}
+
+// AttemptTracker records failed password attempts per slug so that guessing
+// can be throttled across all clients, independently of the per-IP limiter.
+type AttemptTracker interface {
+	// RecordFailure increments the consecutive and total failure counters and
+	// returns the consecutive count. The consecutive counter expires after window.
+	RecordFailure(ctx context.Context, slug string, window time.Duration) (int64, error)
+	Lock(ctx context.Context, slug string, d time.Duration) error
+	// LockedFor returns the remaining lockout, or zero when the slug is not locked.
+	LockedFor(ctx context.Context, slug string) (time.Duration, error)
+	TotalFailures(ctx context.Context, slug string) (int64, error)
+	ResetConsecutive(ctx context.Context, slug string) error
+}
+
+// ClickCounter mirrors the remaining visits of click-limited links in Redis so
+// that exhausted links are refused without a MySQL round trip. It is only a
+// pre-filter: MySQL's conditional update decides whether a visit is allowed.
+type ClickCounter interface {
+	// Decrement takes one visit and returns what is left, which is negative
+	// once the link is exhausted. found is false when no counter is cached.
+	Decrement(ctx context.Context, slug string) (remaining int64, found bool, err error)
+	// Restore gives back a visit taken by Decrement that MySQL did not confirm.
+	Restore(ctx context.Context, slug string) error
+	// Seed caches the remaining visits unless a counter already exists.
+	Seed(ctx context.Context, slug string, remaining int64, ttl time.Duration) error
+	Delete(ctx context.Context, slug string) error
 }
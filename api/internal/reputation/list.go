@@ -0,0 +1,204 @@
+package reputation
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+const (
+	// minHashPrefix is the shortest hex run read as a (rejected) hash prefix
+	// rather than a domain: Safe Browsing prefixes start at 4 bytes.
+	minHashPrefix = 8
+	// maxHostSuffixes and maxPathPrefixes follow the Safe Browsing limits on
+	// the expressions checked per URL.
+	maxHostSuffixes = 5
+	maxPathPrefixes = 4
+)
+
+// list is one parsed threat list. A file may mix the three kinds of entries.
+type list struct {
+	name    string
+	domains map[string]bool
+	urls    map[string]bool
+	// hashes holds full SHA-256 digests.
+	hashes map[string]bool
+	// shortHashes counts the hash prefixes skipped because a prefix alone
+	// cannot tell a listed URL from an innocent one that collides with it.
+	shortHashes int
+}
+
+// parseList reads one entry per line, skipping blanks and # comments:
+//
+//   - a URL starting with http:// or https:// (URLhaus text exports, or the
+//     url column of its CSV exports) lists exactly that URL;
+//   - 64 hex characters list the SHA-256 of a Safe Browsing-style host and
+//     path expression; shorter prefixes, which innocent URLs are expected
+//     to collide with, are skipped;
+//   - anything else is a domain, alone or after an address as in hosts
+//     files ("0.0.0.0 evil.example"), and lists the domain and every
+//     subdomain.
+func parseList(name string, r io.Reader) (*list, error) {
+	l := &list{
+		name:    name,
+		domains: make(map[string]bool),
+		urls:    make(map[string]bool),
+		hashes:  make(map[string]bool),
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, `"`) {
+			line = csvURL(line)
+		}
+		switch {
+		case strings.HasPrefix(line, "http://"), strings.HasPrefix(line, "https://"):
+			if key, ok := urlKey(line); ok {
+				l.urls[key] = true
+			}
+		case isHashPrefix(line):
+			raw, _ := hex.DecodeString(line)
+			if len(raw) != sha256.Size {
+				l.shortHashes++
+				continue
+			}
+			l.hashes[string(raw)] = true
+		default:
+			fields := strings.Fields(line)
+			if domain := normalizeHost(fields[len(fields)-1]); domain != "" {
+				l.domains[domain] = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading threat list %s: %w", name, err)
+	}
+	return l, nil
+}
+
+// csvURL returns the first URL field of a quoted CSV line, or "" when it has
+// none.
+func csvURL(line string) string {
+	for _, field := range strings.Split(line, ",") {
+		field = strings.Trim(field, `" `)
+		if strings.HasPrefix(field, "http://") || strings.HasPrefix(field, "https://") {
+			return field
+		}
+	}
+	return ""
+}
+
+func isHashPrefix(s string) bool {
+	if len(s) < minHashPrefix || len(s) > 2*sha256.Size || len(s)%2 != 0 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// urlKey normalizes raw for exact comparison: lower-case scheme and host, no
+// fragment, and "/" for an empty path.
+func urlKey(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key := strings.ToLower(u.Scheme) + "://" + normalizeHost(u.Host) + path
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key, true
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// match reports whether u is listed.
+func (l *list) match(u *url.URL) bool {
+	host := normalizeHost(u.Hostname())
+	for h := host; h != ""; {
+		if l.domains[h] {
+			return true
+		}
+		_, parent, ok := strings.Cut(h, ".")
+		if !ok {
+			break
+		}
+		h = parent
+	}
+
+	if key, ok := urlKey(u.String()); ok && l.urls[key] {
+		return true
+	}
+
+	if len(l.hashes) == 0 {
+		return false
+	}
+	for _, expr := range expressions(host, u) {
+		if sum := sha256.Sum256([]byte(expr)); l.hashes[string(sum[:])] {
+			return true
+		}
+	}
+	return false
+}
+
+// expressions returns the Safe Browsing host-suffix/path-prefix combinations
+// for u: the exact host plus, for names, up to four suffixes formed from its
+// last five components, each joined with the exact path and query, the exact
+// path, and up to four path prefixes starting at "/".
+func expressions(host string, u *url.URL) []string {
+	hosts := []string{host}
+	if _, err := netip.ParseAddr(host); err != nil {
+		labels := strings.Split(host, ".")
+		if len(labels) > maxHostSuffixes {
+			labels = labels[len(labels)-maxHostSuffixes:]
+		}
+		for i := 0; i < len(labels)-1 && len(hosts) < maxHostSuffixes; i++ {
+			if suffix := strings.Join(labels[i:], "."); suffix != host {
+				hosts = append(hosts, suffix)
+			}
+		}
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	paths := []string{path}
+	if u.RawQuery != "" {
+		paths = append(paths, path+"?"+u.RawQuery)
+	}
+	prefix := "/"
+	paths = append(paths, prefix)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i < len(segments)-1 && len(paths) < maxPathPrefixes+2; i++ {
+		prefix += segments[i] + "/"
+		paths = append(paths, prefix)
+	}
+
+	seen := make(map[string]bool)
+	var out []string
+	for _, h := range hosts {
+		for _, p := range paths {
+			if expr := h + p; !seen[expr] {
+				seen[expr] = true
+				out = append(out, expr)
+			}
+		}
+	}
+	return out
+}
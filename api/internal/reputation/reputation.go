@@ -0,0 +1,135 @@
+// Package reputation flags link destinations that appear on locally stored
+// threat lists, such as Safe Browsing-style full hashes or URLhaus exports.
+package reputation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileChecker matches targets against threat lists read from files. The
+// files can be replaced on disk and are reloaded without a restart.
+type FileChecker struct {
+	paths []string
+
+	mu       sync.RWMutex
+	lists    []*list
+	modTimes map[string]time.Time
+}
+
+// Open loads the lists at paths.
+func Open(paths []string) (*FileChecker, error) {
+	c := &FileChecker{paths: paths}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads every list file again and swaps them in together. A file that
+// fails to load fails the whole reload, keeping the current lists.
+func (c *FileChecker) Reload() error {
+	lists := make([]*list, 0, len(c.paths))
+	modTimes := make(map[string]time.Time, len(c.paths))
+	entries := 0
+	for _, path := range c.paths {
+		l, modTime, err := loadList(path)
+		if err != nil {
+			return err
+		}
+		lists = append(lists, l)
+		modTimes[path] = modTime
+		entries += len(l.domains) + len(l.urls) + len(l.hashes)
+	}
+
+	c.mu.Lock()
+	c.lists = lists
+	c.modTimes = modTimes
+	c.mu.Unlock()
+
+	slog.Info("threat lists loaded", "files", len(lists), "entries", entries)
+	return nil
+}
+
+func loadList(path string) (*list, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("opening threat list: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("reading threat list: %w", err)
+	}
+	l, err := parseList(filepath.Base(path), f)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if l.shortHashes > 0 {
+		slog.Warn("skipped hash prefixes shorter than a full SHA-256", "list", l.name, "count", l.shortHashes)
+	}
+	return l, info.ModTime(), nil
+}
+
+// Watch reloads the lists whenever a file's modification time changes,
+// checking every interval until ctx is cancelled. A failed reload keeps the
+// current lists.
+func (c *FileChecker) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !c.changed() {
+				continue
+			}
+			if err := c.Reload(); err != nil {
+				slog.Warn("reloading threat lists", "error", err)
+			}
+		}
+	}
+}
+
+func (c *FileChecker) changed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, path := range c.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			slog.Warn("checking threat list", "path", path, "error", err)
+			continue
+		}
+		if !info.ModTime().Equal(c.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns the file name of the first list flagging target, or "" when
+// none does.
+func (c *FileChecker) Check(_ context.Context, target string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("parsing target: %w", err)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, l := range c.lists {
+		if l.match(u) {
+			return l.name, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"encurtador/internal/model"
+)
+
+const (
+	defaultReportPage = 50
+	maxReportPage     = 200
+	// manageTokenFingerprintLength is how many hex characters of the manage
+	// token hash operators see: enough to tell owners apart, not to look
+	// anything up.
+	manageTokenFingerprintLength = 12
+	defaultDisableReason         = "disabled by an operator"
+	maxDisableReason             = 255
+)
+
+// AdminLinkInfo is the operator's view of any link. There are no accounts,
+// so the owner is identified by the hash of the creator's IP and a
+// fingerprint of the manage token hash.
+type AdminLinkInfo struct {
+	LinkInfo
+	CreatorID              string
+	ManageTokenFingerprint string
+	Expired                bool
+	Reports                int64
+}
+
+// AdminInspect returns any link, expired or disabled ones included.
+func (s *URLService) AdminInspect(ctx context.Context, slug, actorIP string) (*AdminLinkInfo, error) {
+	url, err := s.findAny(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, url, model.AuditView, model.ActorAdmin, actorIP, nil, nil)
+	return s.adminLinkInfo(ctx, url), nil
+}
+
+// AdminDisable takes a link down with reason, or a default one when empty.
+// Disabling a disabled link changes nothing.
+func (s *URLService) AdminDisable(ctx context.Context, slug, reason, actorIP string) (*AdminLinkInfo, error) {
+	if reason == "" {
+		reason = defaultDisableReason
+	}
+	if len(reason) > maxDisableReason {
+		return nil, ErrInvalidDisableReason
+	}
+	return s.adminChange(ctx, slug, actorIP, model.AuditDisable, func(url *model.URL) (bool, error) {
+		return s.repo.Disable(ctx, url.ID, reason)
+	})
+}
+
+// AdminEnable lifts a link's disabled state.
+func (s *URLService) AdminEnable(ctx context.Context, slug, actorIP string) (*AdminLinkInfo, error) {
+	return s.adminChange(ctx, slug, actorIP, model.AuditEnable, func(url *model.URL) (bool, error) {
+		return s.repo.Enable(ctx, url.ID)
+	})
+}
+
+// AdminExpire expires a link at once without its manage token.
+func (s *URLService) AdminExpire(ctx context.Context, slug, actorIP string) (*AdminLinkInfo, error) {
+	return s.adminChange(ctx, slug, actorIP, model.AuditExpire, func(url *model.URL) (bool, error) {
+		return s.repo.ForceExpire(ctx, url.ID)
+	})
+}
+
+// adminChange applies change to the link holding slug and, when it changed
+// anything, drops the cache entry (and, for an expired link, its click
+// counter) and audits the before and after states.
+func (s *URLService) adminChange(ctx context.Context, slug, actorIP string, action model.AuditAction, change func(*model.URL) (bool, error)) (*AdminLinkInfo, error) {
+	url, err := s.findAny(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := change(url)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return s.adminLinkInfo(ctx, url), nil
+	}
+
+	if action == model.AuditExpire {
+		s.forget(ctx, slug)
+	} else if err := s.cache.Delete(ctx, slug); err != nil {
+		slog.Warn("failed to invalidate cache after admin action", "slug", slug, "action", action, "error", err)
+	}
+	before := snapshot(url)
+	if url, err = s.findAny(ctx, slug); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, url, action, model.ActorAdmin, actorIP, before, snapshot(url))
+	return s.adminLinkInfo(ctx, url), nil
+}
+
+// AdminReports lists abuse reports newest first, for one slug when it is not
+// empty. Pass the smallest ID of a page as beforeID to get the next one.
+func (s *URLService) AdminReports(ctx context.Context, slug string, beforeID uint64, limit int, actorIP string) ([]model.AbuseReport, error) {
+	if limit <= 0 {
+		limit = defaultReportPage
+	}
+	limit = min(limit, maxReportPage)
+	reports, err := s.reports.List(ctx, slug, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, &model.URL{Slug: slug}, model.AuditViewReports, model.ActorAdmin, actorIP, nil, nil)
+	return reports, nil
+}
+
+func (s *URLService) findAny(ctx context.Context, slug string) (*model.URL, error) {
+	url, err := s.repo.FindAny(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if url == nil {
+		return nil, ErrLinkNotFound
+	}
+	return url, nil
+}
+
+func (s *URLService) adminLinkInfo(ctx context.Context, url *model.URL) *AdminLinkInfo {
+	info := &AdminLinkInfo{
+		LinkInfo:               *s.linkInfo(ctx, url),
+		ManageTokenFingerprint: url.ManageTokenHash[:min(manageTokenFingerprintLength, len(url.ManageTokenHash))],
+		Expired:                url.ExpiresAt != nil && !url.ExpiresAt.After(time.Now()),
+	}
+	if url.CreatorID != nil {
+		info.CreatorID = *url.CreatorID
+	}
+	var err error
+	if info.Reports, err = s.reports.Count(ctx, url.ID); err != nil {
+		slog.Warn("failed to count abuse reports", "slug", url.Slug, "error", err)
+	}
+	return info
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"encurtador/internal/model"
+)
+
+const (
+	defaultAuditPage = 50
+	maxAuditPage     = 200
+	// clientIDBytes is how much of the HMAC of a client's IP is kept: enough
+	// to tell clients apart, too little to be worth inverting.
+	clientIDBytes = 16
+)
+
+// auditState is the part of a link recorded around audited actions. Secrets,
+// such as the password hash, are reduced to whether they are set.
+type auditState struct {
+	TargetURL      string            `json:"target_url"`
+	ExpiresAt      *time.Time        `json:"expires_at"`
+	Protected      bool              `json:"protected"`
+	UTM            *model.UTM        `json:"utm,omitempty"`
+	DeviceRules    model.DeviceRules `json:"device_rules,omitempty"`
+	GeoRules       model.GeoRules    `json:"geo_rules,omitempty"`
+	Variants       model.Variants    `json:"variants,omitempty"`
+	StickyVariants bool              `json:"sticky_variants,omitempty"`
+	DisabledAt     *time.Time        `json:"disabled_at,omitempty"`
+	DisabledReason *string           `json:"disabled_reason,omitempty"`
+}
+
+// snapshot captures url's audited state as JSON.
+func snapshot(url *model.URL) model.AuditState {
+	data, err := json.Marshal(auditState{
+		TargetURL:      url.TargetURL,
+		ExpiresAt:      url.ExpiresAt,
+		Protected:      url.PasswordHash != nil,
+		UTM:            url.UTM,
+		DeviceRules:    url.DeviceRules,
+		GeoRules:       url.GeoRules,
+		Variants:       url.Variants,
+		StickyVariants: url.StickyVariants,
+		DisabledAt:     url.DisabledAt,
+		DisabledReason: url.DisabledReason,
+	})
+	if err != nil {
+		slog.Error("encoding audit snapshot", "slug", url.Slug, "error", err)
+		return nil
+	}
+	return data
+}
+
+// audit appends an entry for an action on url. The action has already taken
+// effect, so a failed write is logged rather than returned.
+func (s *URLService) audit(ctx context.Context, url *model.URL, action model.AuditAction, actor model.AuditActor, actorIP string, before, after model.AuditState) {
+	entry := &model.AuditEntry{
+		Slug:   url.Slug,
+		Action: action,
+		Actor:  actor,
+		Before: before,
+		After:  after,
+	}
+	if url.ID != 0 {
+		entry.URLID = &url.ID
+	}
+	if actorIP != "" {
+		id := s.clientID(actorIP)
+		entry.ActorID = &id
+	}
+	if err := s.auditLog.Append(ctx, entry); err != nil {
+		slog.Error("failed to write audit entry", "slug", url.Slug, "action", action, "error", err)
+	}
+}
+
+// clientID identifies an anonymous client (an audited actor, a link's
+// creator or an abuse reporter) by a keyed hash of its IP, so the address is
+// never stored and cannot be recovered by hashing every candidate. The "ip:"
+// prefix leaves room for other kinds of identifiers.
+func (s *URLService) clientID(ip string) string {
+	mac := hmac.New(sha256.New, s.ipHashKey)
+	mac.Write([]byte(ip))
+	return "ip:" + hex.EncodeToString(mac.Sum(nil)[:clientIDBytes])
+}
+
+// AuditTrail returns the audit entries of a link to the holder of its manage
+// token, newest first, without operator lookups. Pass the smallest ID of a page as beforeID to get the
+// next one.
+func (s *URLService) AuditTrail(ctx context.Context, slug, manageToken string, beforeID uint64, limit int) ([]model.AuditEntry, error) {
+	url, err := s.repo.FindByManageToken(ctx, slug, hashManageToken(manageToken))
+	if err != nil {
+		return nil, err
+	}
+	if url == nil {
+		return nil, ErrInvalidManageToken
+	}
+	if limit <= 0 {
+		limit = defaultAuditPage
+	}
+	return s.auditLog.List(ctx, url.ID, beforeID, min(limit, maxAuditPage))
+}
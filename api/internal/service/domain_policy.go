@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"encurtador/internal/model"
+)
+
+// knownShorteners are public URL shorteners whose links may lead back here,
+// refused as targets when Config.RejectShorteners is set.
+var knownShorteners = map[string]bool{
+	"bit.ly":      true,
+	"buff.ly":     true,
+	"cutt.ly":     true,
+	"goo.gl":      true,
+	"is.gd":       true,
+	"ow.ly":       true,
+	"rb.gy":       true,
+	"rebrand.ly":  true,
+	"shorturl.at": true,
+	"t.co":        true,
+	"t.ly":        true,
+	"tiny.cc":     true,
+	"tinyurl.com": true,
+	"v.gd":        true,
+}
+
+// domainPolicy is the compiled form of the operator's domain rules. It is
+// immutable once built and swapped as a whole on refresh.
+type domainPolicy struct {
+	block domainMatcher
+	allow domainMatcher
+	// restricted is set when any allow rule exists, turning the allowlist on.
+	restricted bool
+}
+
+type domainMatcher struct {
+	hosts    map[string]bool
+	suffixes []string
+	prefixes []netip.Prefix
+}
+
+func newDomainPolicy(rules []model.DomainRule) *domainPolicy {
+	p := &domainPolicy{
+		block: domainMatcher{hosts: make(map[string]bool)},
+		allow: domainMatcher{hosts: make(map[string]bool)},
+	}
+	for _, rule := range rules {
+		m := &p.block
+		if rule.List == model.DomainAllow {
+			m = &p.allow
+			p.restricted = true
+		}
+		if !m.add(rule.Pattern) {
+			slog.Warn("ignoring malformed domain rule", "id", rule.ID, "pattern", rule.Pattern)
+		}
+	}
+	return p
+}
+
+// add compiles one pattern and reports whether it was understood.
+func (m *domainMatcher) add(pattern string) bool {
+	pattern = normalizeHost(pattern)
+	switch {
+	case pattern == "":
+		return false
+	case strings.Contains(pattern, "/"):
+		prefix, err := netip.ParsePrefix(pattern)
+		if err != nil {
+			return false
+		}
+		m.prefixes = append(m.prefixes, prefix.Masked())
+	case strings.HasPrefix(pattern, "*."):
+		m.suffixes = append(m.suffixes, pattern[1:])
+	default:
+		if addr, err := netip.ParseAddr(pattern); err == nil {
+			m.prefixes = append(m.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			return true
+		}
+		m.hosts[pattern] = true
+	}
+	return true
+}
+
+func (m *domainMatcher) match(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		for _, prefix := range m.prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	if m.hosts[host] {
+		return true
+	}
+	for _, suffix := range m.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether links may point at host. Block rules win over allow
+// rules.
+func (p *domainPolicy) allows(host string) bool {
+	host = normalizeHost(host)
+	if p.block.match(host) {
+		return false
+	}
+	return !p.restricted || p.allow.match(host)
+}
+
+// normalizeHost lower-cases host and drops the trailing dot of a fully
+// qualified name, so "Example.COM." and "example.com" compare equal.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// LoadDomainRules replaces the in-memory domain policy with the rules
+// currently stored.
+func (s *URLService) LoadDomainRules(ctx context.Context) error {
+	rules, err := s.domains.List(ctx)
+	if err != nil {
+		return err
+	}
+	s.policy.Store(newDomainPolicy(rules))
+	return nil
+}
+
+// RunDomainRefresh periodically reloads the domain rules so operator edits
+// take effect without a restart. Intended to run as a goroutine.
+func (s *URLService) RunDomainRefresh(ctx context.Context) {
+	ticker := time.NewTicker(s.domainRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.LoadDomainRules(ctx); err != nil {
+				slog.Error("refreshing domain rules", "error", err)
+			}
+		}
+	}
+}
+
+// destination is one target of a link, named by its request field.
+type destination struct {
+	field  string
+	target string
+}
+
+// checkDestinations rejects a link whose target or rule targets point at a
+// destination the operator does not allow. Empty targets are skipped so edits
+// only check the fields they change.
+func (s *URLService) checkDestinations(ctx context.Context, target string, device model.DeviceRules, geo model.GeoRules, variants model.Variants) error {
+	fields := []destination{{"target_url", target}}
+	for i, rule := range device {
+		fields = append(fields, destination{fmt.Sprintf("device_rules[%d].target_url", i), rule.TargetURL})
+	}
+	for i, rule := range geo {
+		fields = append(fields, destination{fmt.Sprintf("geo_rules[%d].target_url", i), rule.TargetURL})
+	}
+	for i, v := range variants {
+		fields = append(fields, destination{fmt.Sprintf("variants[%d].target_url", i), v.TargetURL})
+	}
+
+	// One budget covers every DNS lookup of the request.
+	ctx, cancel := context.WithTimeout(ctx, targetLookupTimeout)
+	defer cancel()
+	for _, f := range fields {
+		if err := s.checkDestination(ctx, f.field, f.target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ownHosts returns the normalized hosts short links are served from, so
+// targets on them can be refused before they form a redirect loop.
+func ownHosts(baseURL string, shortDomains []string) map[string]bool {
+	hosts := make(map[string]bool, len(shortDomains)+1)
+	if u, err := neturl.Parse(baseURL); err == nil && u.Hostname() != "" {
+		hosts[normalizeHost(u.Hostname())] = true
+	}
+	for _, domain := range shortDomains {
+		hosts[normalizeHost(domain)] = true
+	}
+	return hosts
+}
+
+func (s *URLService) checkDestination(ctx context.Context, field, target string) error {
+	if target == "" {
+		return nil
+	}
+	u, err := neturl.Parse(target)
+	if err != nil {
+		return fmt.Errorf("%s: %w", field, ErrDestinationBlocked)
+	}
+	host := normalizeHost(u.Hostname())
+	if s.ownHosts[host] {
+		return fmt.Errorf("%s: %w", field, ErrSelfReferential)
+	}
+	if s.rejectShorteners && knownShorteners[strings.TrimPrefix(host, "www.")] {
+		return fmt.Errorf("%s: %w", field, ErrShortenerTarget)
+	}
+	if policy := s.policy.Load(); policy != nil && !policy.allows(host) {
+		return fmt.Errorf("%s: %w", field, ErrDestinationBlocked)
+	}
+	if err := s.private.check(ctx, host); err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	if err := s.checkReputation(ctx, target); err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"encurtador/internal/model"
+)
+
+const (
+	// bcryptMaxPassword is how many bytes of a password bcrypt looks at.
+	bcryptMaxPassword = 72
+	argon2SaltLength  = 16
+	argon2KeyLength   = 32
+	// maxPasswordLength bounds link passwords; Argon2id has no length limit
+	// of its own, unlike bcrypt, which ignored everything past 72 bytes.
+	maxPasswordLength = 1024
+)
+
+// errMalformedHash reports a stored password hash in neither supported format.
+var errMalformedHash = errors.New("malformed password hash")
+
+// Argon2Params are the Argon2id costs for new password hashes. Memory is in
+// KiB. Hashes keep the parameters they were made with, so changing these only
+// affects new hashes and those upgraded on the next successful unlock.
+type Argon2Params struct {
+	Memory      int64
+	Iterations  int64
+	Parallelism int64
+}
+
+// passwordHasher hashes link passwords as Argon2id PHC strings and verifies
+// them, along with the bcrypt hashes stored before Argon2id was adopted.
+// slots bounds the verifications running at once, since each allocates the
+// full Argon2id memory cost and anyone may ask for one.
+type passwordHasher struct {
+	params Argon2Params
+	slots  chan struct{}
+}
+
+// hash returns the PHC string of password with a fresh salt:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+func (h *passwordHasher) hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, uint32(p.Iterations), uint32(p.Memory), uint8(p.Parallelism), argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verify reports whether password matches hash and, when it does, whether
+// hash should be replaced: because it is bcrypt or was made with other
+// Argon2id parameters than the current ones. A bcrypt hash matched by a
+// password longer than bcrypt reads is not replaced, since the bytes past
+// the limit were never checked and would become part of the new password.
+// Returns ErrUnlockBusy at once when every slot is taken.
+func (h *passwordHasher) verify(hash, password string) (ok, rehash bool, err error) {
+	select {
+	case h.slots <- struct{}{}:
+		defer func() { <-h.slots }()
+	default:
+		return false, false, ErrUnlockBusy
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		switch {
+		case err == nil:
+			return true, len(password) <= bcryptMaxPassword, nil
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, false, nil
+		default:
+			return false, false, err
+		}
+	}
+
+	var (
+		version int
+		params  Argon2Params
+	)
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil ||
+		params.Memory < 1 || params.Iterations < 1 || params.Parallelism < 1 || params.Parallelism > 255 {
+		return false, false, errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, errMalformedHash
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return false, false, errMalformedHash
+	}
+
+	got := argon2.IDKey([]byte(password), salt, uint32(params.Iterations), uint32(params.Memory), uint8(params.Parallelism), uint32(len(want)))
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return false, false, nil
+	}
+	return true, params != h.params || len(salt) != argon2SaltLength || len(want) != argon2KeyLength, nil
+}
+
+// hashPassword returns the stored form of a link password, or nil for an
+// empty one.
+func (s *URLService) hashPassword(password string) (*string, error) {
+	if password == "" {
+		return nil, nil
+	}
+	if len(password) > maxPasswordLength {
+		return nil, ErrPasswordTooLong
+	}
+	hash, err := s.passwords.hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("hashing password: %w", err)
+	}
+	return &hash, nil
+}
+
+// upgradePassword replaces the bcrypt or outdated Argon2id hash of a link
+// whose password was just verified. A failure keeps the old hash, which still
+// verifies, and a concurrent password change wins over the upgrade.
+func (s *URLService) upgradePassword(ctx context.Context, slug, password string, cached *model.CachedURL) {
+	hash, err := s.passwords.hash(password)
+	if err != nil {
+		slog.Warn("failed to rehash password", "slug", slug, "error", err)
+		return
+	}
+	replaced, err := s.repo.ReplacePasswordHash(ctx, slug, cached.PasswordHash, hash)
+	if err != nil {
+		slog.Warn("failed to store rehashed password", "slug", slug, "error", err)
+		return
+	}
+	if !replaced {
+		return
+	}
+	if err := s.cache.Delete(ctx, slug); err != nil {
+		slog.Warn("failed to invalidate cache after rehash", "slug", slug, "error", err)
+	}
+	cached.PasswordHash = hash
+}
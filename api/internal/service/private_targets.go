@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// reservedPrefixes are special-purpose ranges not covered by the netip
+// predicates used in reservedAddr: shared address space, benchmarking,
+// documentation, protocol assignments, NAT64 and the old class E.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// internalSuffixes are names that only resolve inside a private network.
+var internalSuffixes = []string{
+	".localhost",
+	".local",
+	".internal",
+	".intranet",
+	".lan",
+	".home.arpa",
+	".localdomain",
+	".corp",
+}
+
+// numericLabel matches a last label that makes browsers read the whole host
+// as an IPv4 address in a legacy form, such as 2130706433 or 0x7f.1.
+var numericLabel = regexp.MustCompile(`^(0x[0-9a-f]*|[0-9]+)$`)
+
+// privateTargets refuses destinations on loopback, private, link-local and
+// other reserved addresses, so a public instance cannot be used to send
+// visitors into their own or the operator's internal network.
+type privateTargets struct {
+	enabled  bool
+	exempt   domainMatcher
+	resolver *net.Resolver
+}
+
+func newPrivateTargets(allow bool, exemptions []string) *privateTargets {
+	p := &privateTargets{
+		enabled:  !allow,
+		exempt:   domainMatcher{hosts: make(map[string]bool)},
+		resolver: net.DefaultResolver,
+	}
+	for _, pattern := range exemptions {
+		if !p.exempt.add(pattern) {
+			slog.Warn("ignoring malformed private target exemption", "pattern", pattern)
+		}
+	}
+	return p
+}
+
+// check returns ErrPrivateTarget when host is, or resolves to, a reserved
+// address, and ErrUnresolvableTarget when it does not resolve in time: such a
+// host could be an internal name, or later point anywhere, so it is refused
+// rather than accepted unchecked.
+func (p *privateTargets) check(ctx context.Context, host string) error {
+	if !p.enabled || p.exempt.match(host) {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if reservedAddr(addr) {
+			return ErrPrivateTarget
+		}
+		return nil
+	}
+	if internalName(host) {
+		return ErrPrivateTarget
+	}
+
+	addrs, err := p.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		slog.Debug("target did not resolve", "host", host, "error", err)
+		return ErrUnresolvableTarget
+	}
+	for _, addr := range addrs {
+		if reservedAddr(addr) && !p.exempt.match(addr.Unmap().String()) {
+			return ErrPrivateTarget
+		}
+	}
+	return nil
+}
+
+func reservedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// internalName reports whether host names a machine on a private network:
+// localhost, a single-label name, a reserved internal suffix, or a legacy
+// numeric IPv4 form that netip does not parse.
+func internalName(host string) bool {
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range internalSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return numericLabel.MatchString(host[strings.LastIndexByte(host, '.')+1:])
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"encurtador/internal/model"
+)
+
+// rescanBatch is how many links the reputation scan loads per query.
+const rescanBatch = 500
+
+// ReputationChecker flags destinations known to be malicious, such as
+// phishing or malware URLs.
+type ReputationChecker interface {
+	// Check returns the name of the source flagging target, or "" when none
+	// does.
+	Check(ctx context.Context, target string) (string, error)
+}
+
+// checkReputation returns ErrMaliciousTarget when target is flagged. A failing
+// checker is logged and the target accepted, so an outage of the checker does
+// not stop link creation.
+func (s *URLService) checkReputation(ctx context.Context, target string) error {
+	if s.reputation == nil {
+		return nil
+	}
+	source, err := s.reputation.Check(ctx, target)
+	if err != nil {
+		slog.Warn("reputation check failed", "error", err)
+		return nil
+	}
+	if source != "" {
+		slog.Warn("refused flagged target", "target", target, "source", source)
+		return ErrMaliciousTarget
+	}
+	return nil
+}
+
+// RunReputationScan re-checks the targets of every live link on start and then
+// periodically, disabling links that have been flagged since they were
+// created. Intended to run as a goroutine; returns at once when no checker is
+// configured.
+func (s *URLService) RunReputationScan(ctx context.Context) {
+	if s.reputation == nil {
+		return
+	}
+	if err := s.rescanLinks(ctx); err != nil {
+		slog.Error("reputation scan failed", "error", err)
+	}
+	ticker := time.NewTicker(s.rescan)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.rescanLinks(ctx); err != nil {
+				slog.Error("reputation scan failed", "error", err)
+			}
+		}
+	}
+}
+
+func (s *URLService) rescanLinks(ctx context.Context) error {
+	var afterID uint64
+	disabled := 0
+	for {
+		urls, err := s.repo.ListServable(ctx, afterID, rescanBatch)
+		if err != nil {
+			return err
+		}
+		for i := range urls {
+			if s.rescanLink(ctx, &urls[i]) {
+				disabled++
+			}
+		}
+		if len(urls) < rescanBatch {
+			break
+		}
+		afterID = urls[len(urls)-1].ID
+	}
+	if disabled > 0 {
+		slog.Info("reputation scan finished", "disabled", disabled)
+	}
+	return nil
+}
+
+// rescanLink disables url when any of its targets is flagged and reports
+// whether it did.
+func (s *URLService) rescanLink(ctx context.Context, url *model.URL) bool {
+	for _, target := range linkTargets(url) {
+		source, err := s.reputation.Check(ctx, target)
+		if err != nil {
+			slog.Warn("reputation check failed", "slug", url.Slug, "error", err)
+			return false
+		}
+		if source == "" {
+			continue
+		}
+		reason := truncateReason("target listed by " + source)
+		disabled, err := s.repo.Disable(ctx, url.ID, reason)
+		if err != nil {
+			slog.Error("failed to disable flagged link", "slug", url.Slug, "error", err)
+			return false
+		}
+		if !disabled {
+			return false
+		}
+		if err := s.cache.Delete(ctx, url.Slug); err != nil {
+			slog.Warn("failed to invalidate cache after disable", "slug", url.Slug, "error", err)
+		}
+		before := snapshot(url)
+		now := time.Now()
+		url.DisabledAt, url.DisabledReason = &now, &reason
+		s.audit(ctx, url, model.AuditDisable, model.ActorSystem, "", before, snapshot(url))
+		slog.Warn("disabled flagged link", "slug", url.Slug, "target", target, "source", source)
+		return true
+	}
+	return false
+}
+
+// truncateReason cuts reason to what the disabled_reason column holds, since
+// source names come from operator-chosen list and file names. The cut never
+// splits a UTF-8 sequence.
+func truncateReason(reason string) string {
+	if len(reason) <= maxDisableReason {
+		return reason
+	}
+	cut := maxDisableReason
+	for cut > 0 && !utf8.RuneStart(reason[cut]) {
+		cut--
+	}
+	return reason[:cut]
+}
+
+// linkTargets lists every destination url can redirect to.
+func linkTargets(url *model.URL) []string {
+	targets := []string{url.TargetURL}
+	for _, rule := range url.DeviceRules {
+		targets = append(targets, rule.TargetURL)
+	}
+	for _, rule := range url.GeoRules {
+		targets = append(targets, rule.TargetURL)
+	}
+	for _, v := range url.Variants {
+		targets = append(targets, v.TargetURL)
+	}
+	return targets
+}
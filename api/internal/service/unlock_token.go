@@ -0,0 +1,47 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unlockSigner issues and checks tokens proving that a visitor recently
+// entered the correct password for a slug. A token is "<expiry>.<mac>" where
+// the MAC covers the slug, the expiry and a fingerprint of the link's current
+// password, so changing the password silently invalidates outstanding tokens.
+type unlockSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func (u *unlockSigner) issue(slug, fingerprint string, now time.Time) (string, time.Time) {
+	expiresAt := now.Add(u.ttl).Truncate(time.Second)
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	return exp + "." + u.mac(slug, exp, fingerprint), expiresAt
+}
+
+func (u *unlockSigner) verify(token, slug, fingerprint string, now time.Time) bool {
+	exp, mac, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() >= unix {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(u.mac(slug, exp, fingerprint)))
+}
+
+func (u *unlockSigner) mac(slug, exp, fingerprint string) string {
+	m := hmac.New(sha256.New, u.secret)
+	m.Write([]byte(slug))
+	m.Write([]byte{0})
+	m.Write([]byte(exp))
+	m.Write([]byte{0})
+	m.Write([]byte(fingerprint))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
@@ -10,10 +10,10 @@ import (
 	"log/slog"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
-
 	"encurtador/internal/model"
 	"encurtador/internal/repository"
 )
@@ -23,50 +23,407 @@ const (
 	manageTokenLength = 32
 	maxCollisionTries = 10
 	maxAutoSlugTries  = 10
-	slugMinLength     = 5
-	slugMaxLength     = 50
-	base62Chars       = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	maxClicksLimit    = 1_000_000
+	maxUTMValueLength = 255
+	maxDeviceRules    = 10
+	maxGeoRules       = 20
+	maxVariants       = 10
+	maxVariantWeight  = 1000
+	// clickLogBuffer is how many clicks may wait for MySQL before new ones
+	// are dropped, so a slow database never holds up redirects.
+	clickLogBuffer       = 1024
+	clickLogFlushTimeout = 5 * time.Second
+	maxActivationWait    = 365 * 24 * time.Hour
+	// permanentCacheTTL bounds the Redis entry of a link that never expires;
+	// it is repopulated from MySQL on the next miss.
+	permanentCacheTTL = 7 * 24 * time.Hour
+	// clickCounterTTL bounds how long a Redis click counter lives; it is
+	// re-seeded from MySQL on the next visit after it lapses.
+	clickCounterTTL = 24 * time.Hour
+	// minSlideFraction caps the slide interval at this fraction of a link's
+	// TTL, so short-lived sliding links are still extended often enough.
+	minSlideFraction = 4
+	// disabledCacheTTL bounds the Redis placeholder of a disabled link, so
+	// re-enabling it directly in MySQL takes effect within this delay.
+	disabledCacheTTL = 5 * time.Minute
+	maxReportDetails = 1000
+	// targetLookupTimeout bounds the DNS lookups of all targets of a request.
+	targetLookupTimeout = 5 * time.Second
+	slugMinLength       = 5
+	slugMaxLength       = 50
+	base62Chars         = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 )
 
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+var variantNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
 var slugPattern = regexp.MustCompile(`^[a-zA-Z0-9-]{` + strconv.Itoa(slugMinLength) + `,` + strconv.Itoa(slugMaxLength) + `}$`)
 
+// reservedSlugs are names the embedded frontend routes as "/<name>/...",
+// which would shadow the paths below a prefix link of that slug. Shorter
+// routes such as /gate and /404 are not valid slugs anyway.
+var reservedSlugs = map[string]bool{
+	"manage": true,
+	"assets": true,
+}
+
+// maxStorableExpiry is the last instant a MySQL TIMESTAMP column can hold.
+var maxStorableExpiry = time.Date(2038, 1, 19, 3, 14, 7, 0, time.UTC)
+
 var (
-	ErrSlugTaken          = errors.New("slug is taken and no alternative could be found")
-	ErrInvalidSlugFormat  = errors.New("slug must be " + strconv.Itoa(slugMinLength) + "-" + strconv.Itoa(slugMaxLength) + " characters: letters, numbers, or hyphens")
-	ErrInvalidTTL         = errors.New("invalid TTL value")
-	ErrInvalidPassword    = errors.New("invalid password")
-	ErrInvalidManageToken = errors.New("invalid manage token")
+	ErrSlugTaken            = errors.New("slug is taken and no alternative could be found")
+	ErrInvalidSlugFormat    = errors.New("slug must be " + strconv.Itoa(slugMinLength) + "-" + strconv.Itoa(slugMaxLength) + " characters: letters, numbers, or hyphens")
+	ErrInvalidTTL           = errors.New("invalid TTL value")
+	ErrInvalidMaxClicks     = errors.New("max_clicks must be between 1 and " + strconv.Itoa(maxClicksLimit))
+	ErrInvalidPassword      = errors.New("invalid password")
+	ErrPasswordTooLong      = errors.New("password must be at most " + strconv.Itoa(maxPasswordLength) + " bytes")
+	ErrInvalidManageToken   = errors.New("invalid manage token")
+	ErrUnlockLocked         = errors.New("too many failed attempts")
+	ErrUnlockBusy           = errors.New("too many password checks in progress")
+	ErrInvalidActivation    = errors.New("activates_at must be at most one year in the future")
+	ErrNotYetActive         = errors.New("link is not active yet")
+	ErrInvalidSliding       = errors.New("sliding expiration requires a relative ttl")
+	ErrInvalidRedirect      = errors.New("redirect_status must be 301, 302, 307 or 308")
+	ErrInvalidPassthrough   = errors.New(`forward_query must be "target" or "request"`)
+	ErrInvalidUTM           = errors.New("utm values must be at most " + strconv.Itoa(maxUTMValueLength) + " characters")
+	ErrUTMConflict          = errors.New("utm and utm_default cannot be combined")
+	ErrInvalidVariants      = errors.New("variants must list 2 to " + strconv.Itoa(maxVariants) + " uniquely named destinations with weights from 1 to " + strconv.Itoa(maxVariantWeight))
+	ErrInvalidGeoRules      = errors.New("geo_rules accepts up to " + strconv.Itoa(maxGeoRules) + " rules, each listing two-letter ISO country codes")
+	ErrInvalidDeviceRules   = errors.New("device_rules accepts up to " + strconv.Itoa(maxDeviceRules) + " rules for ios, android, windows, macos, linux, mobile or desktop")
+	ErrDestinationBlocked   = errors.New("destination domain is not allowed")
+	ErrSelfReferential      = errors.New("target must not point back at this shortener")
+	ErrShortenerTarget      = errors.New("target must not be another URL shortener")
+	ErrPrivateTarget        = errors.New("target must not point at a private, loopback or reserved address")
+	ErrUnresolvableTarget   = errors.New("target host does not resolve")
+	ErrMaliciousTarget      = errors.New("target is listed as malicious")
+	ErrLinkDisabled         = errors.New("link has been disabled")
+	ErrLinkNotFound         = errors.New("link not found")
+	ErrInvalidDisableReason = errors.New("reason must be at most " + strconv.Itoa(maxDisableReason) + " characters")
+	ErrInvalidReport        = errors.New("reason must be phishing, malware, spam, illegal or other, with details of at most " + strconv.Itoa(maxReportDetails) + " characters")
 )
 
+// NotActiveError reports that a link exists but only starts redirecting at
+// ActivatesAt. It matches ErrNotYetActive with errors.Is.
+type NotActiveError struct {
+	ActivatesAt time.Time
+}
+
+func (e *NotActiveError) Error() string {
+	return fmt.Sprintf("%s, activates at %s", ErrNotYetActive, e.ActivatesAt.Format(time.RFC3339))
+}
+
+func (e *NotActiveError) Unwrap() error {
+	return ErrNotYetActive
+}
+
+// LockoutError reports that password attempts for a slug are temporarily
+// refused. It matches ErrUnlockLocked with errors.Is.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", ErrUnlockLocked, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrUnlockLocked
+}
+
+// LockoutPolicy controls per-slug brute-force protection. Once Threshold
+// consecutive failures accumulate within Window, the slug is locked for
+// BaseLockout, doubling with every further failure up to MaxLockout.
+type LockoutPolicy struct {
+	Threshold   int64
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// Duration returns how long to lock a slug after the given number of
+// consecutive failures, or zero when the threshold has not been reached.
+func (p LockoutPolicy) Duration(failures int64) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+	d := p.BaseLockout
+	for i := p.Threshold; i < failures && d < p.MaxLockout; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxLockout)
+}
+
+// TTLPolicy bounds link lifetimes. Lifetimes count from activation, and
+// AllowPermanent lets clients request links that never expire.
+type TTLPolicy struct {
+	Min            time.Duration
+	Max            time.Duration
+	AllowPermanent bool
+}
+
+// Config holds the settings of URLService and its optional dependencies.
+type Config struct {
+	BaseURL string
+	TTL     TTLPolicy
+	Lockout LockoutPolicy
+	// UnlockTokenSecret keys the HMAC of remember-unlock tokens; UnlockTokenTTL
+	// is how long such a token lets a visitor skip the password gate.
+	UnlockTokenSecret []byte
+	UnlockTokenTTL    time.Duration
+	// SlideInterval is the least time between two extensions of the same
+	// sliding link, bounding its MySQL writes to one per interval.
+	SlideInterval time.Duration
+	// DomainRefresh is how often the domain blocklist and allowlist are
+	// reloaded from MySQL.
+	DomainRefresh time.Duration
+	// ShortDomains are hosts besides BaseURL's that serve short links; like
+	// BaseURL, they are refused as targets. RejectShorteners also refuses
+	// well-known public shorteners, which could chain back to this one.
+	ShortDomains     []string
+	RejectShorteners bool
+	// AllowPrivateTargets turns off the check refusing targets on private
+	// and reserved addresses. PrivateTargetExemptions lists hosts, "*."
+	// wildcards and CIDRs the check lets through.
+	AllowPrivateTargets     bool
+	PrivateTargetExemptions []string
+	// Reputation, when set, refuses flagged targets and is used every
+	// RescanInterval to disable live links whose targets became flagged.
+	Reputation     ReputationChecker
+	RescanInterval time.Duration
+	// Argon2 sets the costs of new password hashes.
+	Argon2 Argon2Params
+	// MaxPasswordChecks bounds the password verifications running at once,
+	// each of which holds the Argon2id memory cost.
+	MaxPasswordChecks int
+	// IPHashSecret keys the hash identifying clients by IP in the audit log,
+	// link creators and abuse reports.
+	IPHashSecret []byte
+}
+
+// UnlockResult is returned by a successful VerifyPassword. Token is empty for
+// links without a password. Link is the unlocked link, for callers that shape
+// the final destination of the visit.
+type UnlockResult struct {
+	Link           *model.CachedURL
+	TargetURL      string
+	Token          string
+	TokenExpiresAt time.Time
+}
+
 type CreateRequest struct {
 	TargetURL string
 	Slug      string
 	TTL       model.TTL
 	Password  string
+	// MaxClicks, when set, expires the link after that many visits.
+	MaxClicks *int64
+	// ActivatesAt, when in the future, delays redirects until that moment.
+	// The TTL then counts from activation rather than from creation.
+	ActivatesAt *time.Time
+	// ExpiresAt is an absolute alternative to TTL; exactly one must be set.
+	ExpiresAt *time.Time
+	// Sliding pushes the expiration forward by the TTL on every visit, so
+	// the link only expires after a full TTL without use.
+	Sliding bool
+	// RedirectStatus, when set, overrides the server's default status.
+	RedirectStatus *int
+	// QueryPassthrough, when set, forwards each visit's query string.
+	QueryPassthrough *model.QueryPassthrough
+	// PrefixMatch appends any path below the short link to the target.
+	PrefixMatch bool
+	// UTM, when set, replaces the server's default campaign parameters.
+	UTM *model.UTM
+	// DeviceRules and GeoRules send visitors on matching platforms or from
+	// matching countries to other targets.
+	DeviceRules model.DeviceRules
+	GeoRules    model.GeoRules
+	// Variants split the remaining visitors across weighted destinations;
+	// StickyVariants keeps each visitor on the first variant served.
+	Variants       model.Variants
+	StickyVariants bool
+	// CreatorIP is the client that asked for the link; operators see a hash
+	// of it.
+	CreatorIP string
+}
+
+// UpdateRequest lists the owner-editable fields of a link; nil fields are
+// left unchanged.
+type UpdateRequest struct {
+	TargetURL *string
+	UTM       *model.UTM
+	// UTMDefault drops the link's own UTM parameters so the server's default
+	// applies again. It cannot be combined with UTM.
+	UTMDefault     bool
+	DeviceRules    *model.DeviceRules
+	GeoRules       *model.GeoRules
+	Variants       *model.Variants
+	StickyVariants *bool
 }
 
 type CreateResult struct {
-	Slug        string
-	ShortURL    string
-	ExpiresAt   time.Time
-	Protected   bool
-	ManageToken string
+	Slug             string
+	ShortURL         string
+	ExpiresAt        *time.Time
+	Protected        bool
+	MaxClicks        *int64
+	ActivatesAt      *time.Time
+	SlidingTTL       time.Duration
+	RedirectStatus   *int
+	QueryPassthrough *model.QueryPassthrough
+	PrefixMatch      bool
+	UTM              *model.UTM
+	DeviceRules      model.DeviceRules
+	GeoRules         model.GeoRules
+	Variants         model.Variants
+	StickyVariants   bool
+	ManageToken      string
+}
+
+// LinkInfo is the owner's view of a link, returned to manage-token holders.
+type LinkInfo struct {
+	Slug             string
+	TargetURL        string
+	ExpiresAt        *time.Time
+	CreatedAt        time.Time
+	Protected        bool
+	MaxClicks        *int64
+	ClicksRemaining  *int64
+	ActivatesAt      *time.Time
+	SlidingTTL       time.Duration
+	RedirectStatus   *int
+	QueryPassthrough *model.QueryPassthrough
+	PrefixMatch      bool
+	UTM              *model.UTM
+	DeviceRules      model.DeviceRules
+	GeoRules         model.GeoRules
+	Variants         model.Variants
+	StickyVariants   bool
+	Clicks           int64
+	VariantClicks    map[string]int64
+	DisabledAt       *time.Time
+	DisabledReason   string
+	FailedAttempts   int64
+	LockedFor        time.Duration
+}
+
+// LinkPreview is the public description of a link shown before following it.
+// TargetURL is empty whenever the target must stay hidden: for protected links
+// and for links that are not active yet.
+type LinkPreview struct {
+	Slug            string
+	Disabled        bool
+	TargetURL       string
+	ExpiresAt       *time.Time
+	ActivatesAt     *time.Time
+	Protected       bool
+	LimitedClicks   bool
+	VariesByVisitor bool
 }
 
 type URLService struct {
-	repo    repository.URLRepository
-	cache   repository.URLCache
-	baseURL string
+	repo          repository.URLRepository
+	cache         repository.URLCache
+	attempts      repository.AttemptTracker
+	clicks        repository.ClickCounter
+	clickLog      repository.ClickLog
+	domains       repository.DomainRuleRepository
+	reports       repository.AbuseReportRepository
+	auditLog      repository.AuditLog
+	ipHashKey     []byte
+	passwords     *passwordHasher
+	logQueue      chan model.Click
+	policy        atomic.Pointer[domainPolicy]
+	baseURL       string
+	ttl           TTLPolicy
+	lockout       LockoutPolicy
+	unlock        *unlockSigner
+	slide         time.Duration
+	domainRefresh time.Duration
+	// ownHosts are the hosts short links are served from.
+	ownHosts         map[string]bool
+	rejectShorteners bool
+	private          *privateTargets
+	reputation       ReputationChecker
+	rescan           time.Duration
 }
 
-func NewURLService(repo repository.URLRepository, cache repository.URLCache, baseURL string) *URLService {
-	return &URLService{repo: repo, cache: cache, baseURL: baseURL}
+func NewURLService(repo repository.URLRepository, cache repository.URLCache, attempts repository.AttemptTracker, clicks repository.ClickCounter, clickLog repository.ClickLog, domains repository.DomainRuleRepository, reports repository.AbuseReportRepository, auditLog repository.AuditLog, cfg Config) *URLService {
+	return &URLService{
+		repo:             repo,
+		cache:            cache,
+		attempts:         attempts,
+		clicks:           clicks,
+		clickLog:         clickLog,
+		domains:          domains,
+		reports:          reports,
+		auditLog:         auditLog,
+		ipHashKey:        cfg.IPHashSecret,
+		passwords:        &passwordHasher{params: cfg.Argon2, slots: make(chan struct{}, max(cfg.MaxPasswordChecks, 1))},
+		logQueue:         make(chan model.Click, clickLogBuffer),
+		baseURL:          cfg.BaseURL,
+		ttl:              cfg.TTL,
+		lockout:          cfg.Lockout,
+		unlock:           &unlockSigner{secret: cfg.UnlockTokenSecret, ttl: cfg.UnlockTokenTTL},
+		slide:            cfg.SlideInterval,
+		domainRefresh:    cfg.DomainRefresh,
+		ownHosts:         ownHosts(cfg.BaseURL, cfg.ShortDomains),
+		rejectShorteners: cfg.RejectShorteners,
+		private:          newPrivateTargets(cfg.AllowPrivateTargets, cfg.PrivateTargetExemptions),
+		reputation:       cfg.Reputation,
+		rescan:           cfg.RescanInterval,
+	}
 }
 
 func (s *URLService) Create(ctx context.Context, req CreateRequest) (*CreateResult, error) {
-	ttlDuration, ok := model.ValidTTLs[req.TTL]
+	if req.MaxClicks != nil && (*req.MaxClicks < 1 || *req.MaxClicks > maxClicksLimit) {
+		return nil, ErrInvalidMaxClicks
+	}
+	if req.RedirectStatus != nil && !model.ValidRedirectStatuses[*req.RedirectStatus] {
+		return nil, ErrInvalidRedirect
+	}
+	if p := req.QueryPassthrough; p != nil && *p != model.QueryTargetWins && *p != model.QueryRequestWins {
+		return nil, ErrInvalidPassthrough
+	}
+	if !validUTM(req.UTM) {
+		return nil, ErrInvalidUTM
+	}
+	if !validDeviceRules(req.DeviceRules) {
+		return nil, ErrInvalidDeviceRules
+	}
+	geoRules, ok := normalizeGeoRules(req.GeoRules)
 	if !ok {
-		return nil, ErrInvalidTTL
+		return nil, ErrInvalidGeoRules
+	}
+	if len(req.Variants) > 0 && !validVariants(req.Variants) {
+		return nil, ErrInvalidVariants
+	}
+	if err := s.checkDestinations(ctx, req.TargetURL, req.DeviceRules, req.GeoRules, req.Variants); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start := now
+	var activatesAt *time.Time
+	if req.ActivatesAt != nil && req.ActivatesAt.After(now) {
+		if req.ActivatesAt.Sub(now) > maxActivationWait {
+			return nil, ErrInvalidActivation
+		}
+		activatesAt = req.ActivatesAt
+		start = *req.ActivatesAt
+	}
+
+	expiresAt, err := s.expiry(req, start)
+	if err != nil {
+		return nil, err
+	}
+	var slidingTTL *int64
+	if req.Sliding {
+		if req.ExpiresAt != nil || expiresAt == nil {
+			return nil, ErrInvalidSliding
+		}
+		seconds := int64(expiresAt.Sub(start) / time.Second)
+		slidingTTL = &seconds
 	}
 
 	slug, err := s.resolveSlug(ctx, req.Slug)
@@ -74,14 +431,9 @@ func (s *URLService) Create(ctx context.Context, req CreateRequest) (*CreateResu
 		return nil, err
 	}
 
-	var passwordHash *string
-	if req.Password != "" {
-		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return nil, fmt.Errorf("hashing password: %w", err)
-		}
-		h := string(hash)
-		passwordHash = &h
+	passwordHash, err := s.hashPassword(req.Password)
+	if err != nil {
+		return nil, err
 	}
 
 	manageToken, manageTokenHash, err := generateManageToken()
@@ -89,30 +441,62 @@ func (s *URLService) Create(ctx context.Context, req CreateRequest) (*CreateResu
 		return nil, fmt.Errorf("generating manage token: %w", err)
 	}
 
-	expiresAt := time.Now().Add(ttlDuration)
 	url := &model.URL{
-		Slug:            slug,
-		TargetURL:       req.TargetURL,
-		PasswordHash:    passwordHash,
-		ManageTokenHash: manageTokenHash,
-		ExpiresAt:       expiresAt,
+		Slug:              slug,
+		TargetURL:         req.TargetURL,
+		PasswordHash:      passwordHash,
+		ManageTokenHash:   manageTokenHash,
+		MaxClicks:         req.MaxClicks,
+		ClicksRemaining:   req.MaxClicks,
+		ActivatesAt:       activatesAt,
+		SlidingTTLSeconds: slidingTTL,
+		RedirectStatus:    req.RedirectStatus,
+		QueryPassthrough:  req.QueryPassthrough,
+		PrefixMatch:       req.PrefixMatch,
+		UTM:               req.UTM,
+		DeviceRules:       req.DeviceRules,
+		GeoRules:          geoRules,
+		Variants:          req.Variants,
+		StickyVariants:    req.StickyVariants,
+		ExpiresAt:         expiresAt,
+	}
+	if req.CreatorIP != "" {
+		id := s.clientID(req.CreatorIP)
+		url.CreatorID = &id
 	}
 
 	if err := s.repo.Create(ctx, url); err != nil {
 		return nil, err
 	}
+	s.audit(ctx, url, model.AuditCreate, model.ActorOwner, req.CreatorIP, nil, snapshot(url))
 
 	// Cache write failure is non-fatal: the redirect path will fall back to MySQL.
-	if err := s.cache.Set(ctx, slug, url.ToCached(), ttlDuration); err != nil {
+	if err := s.cache.Set(ctx, slug, url.ToCached(now), cacheTTL(url, now)); err != nil {
 		slog.Warn("failed to pre-warm cache", "slug", slug, "error", err)
 	}
+	if req.MaxClicks != nil {
+		if err := s.clicks.Seed(ctx, slug, *req.MaxClicks, clickCounterTTL); err != nil {
+			slog.Warn("failed to seed click counter", "slug", slug, "error", err)
+		}
+	}
 
 	return &CreateResult{
-		Slug:        slug,
-		ShortURL:    s.baseURL + "/" + slug,
-		ExpiresAt:   expiresAt,
-		Protected:   passwordHash != nil,
-		ManageToken: manageToken,
+		Slug:             slug,
+		ShortURL:         s.baseURL + "/" + slug,
+		ExpiresAt:        expiresAt,
+		Protected:        passwordHash != nil,
+		MaxClicks:        req.MaxClicks,
+		ActivatesAt:      activatesAt,
+		SlidingTTL:       url.SlidingTTL(),
+		RedirectStatus:   req.RedirectStatus,
+		QueryPassthrough: req.QueryPassthrough,
+		PrefixMatch:      req.PrefixMatch,
+		UTM:              req.UTM,
+		DeviceRules:      req.DeviceRules,
+		GeoRules:         geoRules,
+		Variants:         req.Variants,
+		StickyVariants:   req.StickyVariants,
+		ManageToken:      manageToken,
 	}, nil
 }
 
@@ -120,33 +504,261 @@ func (s *URLService) Resolve(ctx context.Context, slug string) (*model.CachedURL
 	return s.lookupCached(ctx, slug)
 }
 
-func (s *URLService) VerifyPassword(ctx context.Context, slug, password string) (string, error) {
+// VerifyPassword checks password against a protected link and, on success,
+// issues a token that lets the visitor skip the gate until it expires.
+// Returns nil without an error when the slug does not exist or has expired.
+// The visit is not recorded: callers call RecordVisit once the visit's
+// destination is accepted, so a refused path does not use up a click.
+func (s *URLService) VerifyPassword(ctx context.Context, slug, password string) (*UnlockResult, error) {
 	cached, err := s.lookupCached(ctx, slug)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if cached == nil {
-		return "", nil
+		return nil, nil
+	}
+	if cached.Disabled {
+		return nil, ErrLinkDisabled
+	}
+	if cached.Pending(time.Now()) {
+		return nil, &NotActiveError{ActivatesAt: *cached.ActivatesAt}
 	}
 	if !cached.Protected {
-		return cached.TargetURL, nil
+		return &UnlockResult{Link: cached, TargetURL: cached.TargetURL}, nil
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(cached.PasswordHash), []byte(password)); err != nil {
-		return "", ErrInvalidPassword
+	// The frontend gate probes with an empty password to detect unprotected
+	// links; that is not a guess and must not count toward the lockout.
+	if password == "" {
+		return nil, ErrInvalidPassword
 	}
-	return cached.TargetURL, nil
+	if len(password) > maxPasswordLength {
+		return nil, ErrPasswordTooLong
+	}
+
+	// Attempt tracking fails open: if Redis is unavailable the per-IP limiter
+	// is still in front of this path.
+	lockedFor, err := s.attempts.LockedFor(ctx, slug)
+	if err != nil {
+		slog.Warn("failed to read unlock lockout", "slug", slug, "error", err)
+	}
+	if lockedFor > 0 {
+		return nil, &LockoutError{RetryAfter: lockedFor}
+	}
+
+	ok, rehash, err := s.passwords.verify(cached.PasswordHash, password)
+	if err != nil {
+		return nil, fmt.Errorf("verifying password: %w", err)
+	}
+	if !ok {
+		return nil, s.recordFailedAttempt(ctx, slug)
+	}
+
+	if err := s.attempts.ResetConsecutive(ctx, slug); err != nil {
+		slog.Warn("failed to reset unlock attempts", "slug", slug, "error", err)
+	}
+	if rehash {
+		s.upgradePassword(ctx, slug, password, cached)
+	}
+
+	token, expiresAt := s.unlock.issue(slug, cached.PasswordHash, time.Now())
+	return &UnlockResult{
+		Link:           cached,
+		TargetURL:      cached.TargetURL,
+		Token:          token,
+		TokenExpiresAt: expiresAt,
+	}, nil
+}
+
+// RecordVisit must be called right before a link's target is revealed. It
+// takes one visit from a click-limited link and extends a sliding link's
+// expiration. Returns false when no visits are left.
+func (s *URLService) RecordVisit(ctx context.Context, slug string, cached *model.CachedURL) (bool, error) {
+	ok, err := s.consumeClick(ctx, slug, cached)
+	if err != nil || !ok {
+		return false, err
+	}
+	s.extendSliding(ctx, slug, cached)
+	return true, nil
+}
+
+// consumeClick takes one visit from a click-limited link. The Redis counter refuses exhausted
+// links cheaply; MySQL's conditional update is what guarantees a one-time link
+// is never served twice, even under concurrent requests. Returns false when no
+// visits are left. Links without max_clicks always return true.
+func (s *URLService) consumeClick(ctx context.Context, slug string, cached *model.CachedURL) (bool, error) {
+	if !cached.LimitedClicks {
+		return true, nil
+	}
+
+	remaining, found, err := s.clicks.Decrement(ctx, slug)
+	if err != nil {
+		slog.Warn("click counter unavailable, falling back to db", "slug", slug, "error", err)
+	}
+	if found && remaining < 0 {
+		return false, nil
+	}
+
+	consumed, left, err := s.repo.ConsumeClick(ctx, slug)
+	if err != nil {
+		if found {
+			if err := s.clicks.Restore(ctx, slug); err != nil {
+				slog.Warn("failed to restore click counter", "slug", slug, "error", err)
+			}
+		}
+		return false, err
+	}
+
+	if !consumed || left == 0 {
+		// The link is exhausted and now expired in MySQL; drop it from Redis
+		// so later visits miss the cache and resolve to not found.
+		s.forget(ctx, slug)
+		return consumed, nil
+	}
+	if !found {
+		if err := s.clicks.Seed(ctx, slug, left, clickCounterTTL); err != nil {
+			slog.Warn("failed to seed click counter", "slug", slug, "error", err)
+		}
+	}
+	return true, nil
+}
+
+// extendSliding moves a sliding link's expiration to one TTL from now, at most
+// once per slide interval. Between extensions the stored expiration is still at
+// least a TTL minus one interval away, which is the precision traded for not
+// writing to MySQL on every visit. The Redis entry is dropped after each
+// extension and reloaded with the new expiration, so a cached link never
+// outlives or predeceases its row. Failures only delay the extension; the
+// visit itself is still served.
+func (s *URLService) extendSliding(ctx context.Context, slug string, cached *model.CachedURL) {
+	if cached.SlidingTTLSeconds <= 0 {
+		return
+	}
+	ttl := time.Duration(cached.SlidingTTLSeconds) * time.Second
+	interval := min(s.slide, ttl/minSlideFraction)
+
+	// Without the Redis claim every visit would write to MySQL, so a Redis
+	// failure skips the extension instead of failing open.
+	claimed, err := s.cache.ClaimSlide(ctx, slug, interval)
+	if err != nil {
+		slog.Warn("failed to claim sliding extension", "slug", slug, "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	until := time.Now().Add(ttl)
+	if until.After(maxStorableExpiry) {
+		return
+	}
+	extended, err := s.repo.ExtendExpiry(ctx, slug, until)
+	if err != nil {
+		slog.Warn("failed to extend sliding link", "slug", slug, "error", err)
+		return
+	}
+	if !extended {
+		return
+	}
+	if err := s.cache.Delete(ctx, slug); err != nil {
+		slog.Warn("failed to invalidate cache after extension", "slug", slug, "error", err)
+	}
+}
+
+// LogClick queues a revealed target for the owner's analytics. It never
+// blocks: when the queue is full the click is dropped and logged.
+func (s *URLService) LogClick(slug, variant string) {
+	select {
+	case s.logQueue <- model.Click{Slug: slug, Variant: variant, ClickedAt: time.Now()}:
+	default:
+		slog.Warn("click log queue full, dropping click", "slug", slug)
+	}
+}
+
+// RunClickLog writes queued clicks to MySQL until ctx is cancelled, then
+// flushes what is already queued.
+func (s *URLService) RunClickLog(ctx context.Context) {
+	for {
+		select {
+		case click := <-s.logQueue:
+			s.writeClick(ctx, click)
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), clickLogFlushTimeout)
+			defer cancel()
+			for {
+				select {
+				case click := <-s.logQueue:
+					s.writeClick(flushCtx, click)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *URLService) writeClick(ctx context.Context, click model.Click) {
+	if err := s.clickLog.Record(ctx, click); err != nil {
+		slog.Warn("failed to record click", "slug", click.Slug, "error", err)
+	}
+}
+
+// forget removes every cached trace of a link that is no longer served.
+func (s *URLService) forget(ctx context.Context, slug string) {
+	if err := s.cache.Delete(ctx, slug); err != nil {
+		slog.Warn("failed to invalidate cache", "slug", slug, "error", err)
+	}
+	if err := s.clicks.Delete(ctx, slug); err != nil {
+		slog.Warn("failed to delete click counter", "slug", slug, "error", err)
+	}
+}
+
+// UnlockTokenValid reports whether token, issued by VerifyPassword, still
+// grants access to the protected link described by cached. Tokens stop
+// validating once the password changes; links expired via ExpireEarly no
+// longer resolve at all, so their tokens are moot.
+func (s *URLService) UnlockTokenValid(slug string, cached *model.CachedURL, token string) bool {
+	if token == "" || !cached.Protected {
+		return false
+	}
+	return s.unlock.verify(token, slug, cached.PasswordHash, time.Now())
+}
+
+// recordFailedAttempt counts a wrong password and locks the slug once the
+// lockout policy says so. It returns the error VerifyPassword should surface.
+func (s *URLService) recordFailedAttempt(ctx context.Context, slug string) error {
+	failures, err := s.attempts.RecordFailure(ctx, slug, s.lockout.Window)
+	if err != nil {
+		slog.Warn("failed to record unlock attempt", "slug", slug, "error", err)
+		return ErrInvalidPassword
+	}
+
+	d := s.lockout.Duration(failures)
+	if d == 0 {
+		return ErrInvalidPassword
+	}
+	if err := s.attempts.Lock(ctx, slug, d); err != nil {
+		slog.Warn("failed to lock slug", "slug", slug, "error", err)
+		return ErrInvalidPassword
+	}
+	slog.Warn("slug locked after failed unlock attempts", "slug", slug, "failures", failures, "lockout", d)
+	return &LockoutError{RetryAfter: d}
 }
 
 // lookupCached implements the cache-aside pattern: it tries Redis first, then
 // falls back to MySQL and repopulates the cache on a miss. Returns nil without
-// an error when the slug does not exist or has expired.
+// an error when the slug does not exist or has expired. Links that are not
+// active yet come back as a placeholder; check CachedURL.Pending.
 func (s *URLService) lookupCached(ctx context.Context, slug string) (*model.CachedURL, error) {
+	now := time.Now()
 	cached, err := s.cache.Get(ctx, slug)
 	if err != nil {
 		slog.Warn("cache get failed, falling back to db", "slug", slug, "error", err)
 	}
-	if cached != nil {
+	// A placeholder outliving its activation time (Redis expiry is not exact)
+	// has no target, so it is treated as a miss and replaced from MySQL, as is
+	// a protected entry whose hash is sealed while sealing is off.
+	if cached != nil && (cached.ActivatesAt == nil || cached.Pending(now)) && (!cached.Protected || cached.PasswordHash != "") {
 		return cached, nil
 	}
 
@@ -158,24 +770,310 @@ func (s *URLService) lookupCached(ctx context.Context, slug string) (*model.Cach
 		return nil, nil
 	}
 
-	remaining := time.Until(url.ExpiresAt)
-	if remaining <= 0 {
+	ttl := cacheTTL(url, now)
+	if ttl <= 0 {
 		return nil, nil
 	}
 
-	cached = url.ToCached()
-	if err := s.cache.Set(ctx, slug, cached, remaining); err != nil {
+	cached = url.ToCached(now)
+	if err := s.cache.Set(ctx, slug, cached, ttl); err != nil {
 		slog.Warn("failed to populate cache", "slug", slug, "error", err)
 	}
 
 	return cached, nil
 }
 
-func (s *URLService) ExpireEarly(ctx context.Context, slug, manageToken string) error {
-	sum := sha256.Sum256([]byte(manageToken))
-	hash := hex.EncodeToString(sum[:])
+// expiry resolves the expiration time of a new link whose lifetime starts at
+// start, from either a relative TTL or an absolute ExpiresAt. It returns nil
+// for a permanent link. Every rejection wraps ErrInvalidTTL.
+func (s *URLService) expiry(req CreateRequest, start time.Time) (*time.Time, error) {
+	if (req.TTL == "") == (req.ExpiresAt == nil) {
+		return nil, fmt.Errorf("%w: exactly one of ttl or expires_at is required", ErrInvalidTTL)
+	}
+
+	var expiresAt time.Time
+	switch {
+	case req.ExpiresAt != nil:
+		expiresAt = *req.ExpiresAt
+	case req.TTL == model.TTLNever:
+		if !s.ttl.AllowPermanent {
+			return nil, fmt.Errorf("%w: links without expiration are disabled", ErrInvalidTTL)
+		}
+		return nil, nil
+	default:
+		d, err := time.ParseDuration(string(req.TTL))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a duration such as 90m or 36h", ErrInvalidTTL, req.TTL)
+		}
+		expiresAt = start.Add(d)
+	}
+
+	lifetime := expiresAt.Sub(start)
+	if lifetime < s.ttl.Min || lifetime > s.ttl.Max {
+		return nil, fmt.Errorf("%w: lifetime must be between %s and %s", ErrInvalidTTL, s.ttl.Min, s.ttl.Max)
+	}
+	if expiresAt.After(maxStorableExpiry) {
+		return nil, fmt.Errorf("%w: expiration must be before %s", ErrInvalidTTL, maxStorableExpiry.Format(time.RFC3339))
+	}
+	return &expiresAt, nil
+}
+
+// cacheTTL returns how long url's cache entry may live from now: until the
+// link expires or, for a link that is not active yet, until it activates so
+// the placeholder is replaced by the full payload at the boundary.
+func cacheTTL(url *model.URL, now time.Time) time.Duration {
+	ttl := permanentCacheTTL
+	if url.ExpiresAt != nil {
+		ttl = url.ExpiresAt.Sub(now)
+	}
+	if url.DisabledAt != nil {
+		return min(ttl, disabledCacheTTL)
+	}
+	if !url.Active(now) {
+		ttl = min(ttl, url.ActivatesAt.Sub(now))
+	}
+	return ttl
+}
+
+// Preview describes a link to anyone holding its slug without counting a
+// visit. It reads the same cache entry as a redirect, so previews cost MySQL
+// no more than visits do. Returns nil without an error when the slug does not
+// exist or has expired.
+func (s *URLService) Preview(ctx context.Context, slug string) (*LinkPreview, error) {
+	cached, err := s.lookupCached(ctx, slug)
+	if err != nil || cached == nil {
+		return nil, err
+	}
+
+	preview := &LinkPreview{
+		Slug:          slug,
+		ExpiresAt:     cached.ExpiresAt,
+		Protected:     cached.Protected,
+		LimitedClicks: cached.LimitedClicks,
+		Disabled:      cached.Disabled,
+	}
+	if preview.Disabled {
+		return preview, nil
+	}
+	if cached.Pending(time.Now()) {
+		preview.ActivatesAt = cached.ActivatesAt
+		return preview, nil
+	}
+	// Revealing the target of a click-limited link would let anyone use it
+	// without spending a click.
+	if !preview.Protected && !preview.LimitedClicks {
+		preview.TargetURL = cached.TargetURL
+		preview.VariesByVisitor = cached.VariesByVisitor()
+	}
+	return preview, nil
+}
+
+// Inspect returns the owner's view of a link, including how many wrong
+// passwords have been tried against it.
+func (s *URLService) Inspect(ctx context.Context, slug, manageToken string) (*LinkInfo, error) {
+	url, err := s.repo.FindByManageToken(ctx, slug, hashManageToken(manageToken))
+	if err != nil {
+		return nil, err
+	}
+	if url == nil {
+		return nil, ErrInvalidManageToken
+	}
+	return s.linkInfo(ctx, url), nil
+}
+
+// Update changes the owner-editable fields of a link and returns its new
+// state. The cache entry is dropped so the next visit reloads it from MySQL.
+func (s *URLService) Update(ctx context.Context, slug, manageToken, actorIP string, req UpdateRequest) (*LinkInfo, error) {
+	if req.UTMDefault && req.UTM != nil {
+		return nil, ErrUTMConflict
+	}
+	if !validUTM(req.UTM) {
+		return nil, ErrInvalidUTM
+	}
+	if req.DeviceRules != nil && !validDeviceRules(*req.DeviceRules) {
+		return nil, ErrInvalidDeviceRules
+	}
+	var geoRules model.GeoRules
+	if req.GeoRules != nil {
+		var ok bool
+		if geoRules, ok = normalizeGeoRules(*req.GeoRules); !ok {
+			return nil, ErrInvalidGeoRules
+		}
+	}
+	if req.Variants != nil && len(*req.Variants) > 0 && !validVariants(*req.Variants) {
+		return nil, ErrInvalidVariants
+	}
+	var (
+		target      string
+		deviceRules model.DeviceRules
+		variants    model.Variants
+	)
+	if req.TargetURL != nil {
+		target = *req.TargetURL
+	}
+	if req.DeviceRules != nil {
+		deviceRules = *req.DeviceRules
+	}
+	if req.Variants != nil {
+		variants = *req.Variants
+	}
+	if err := s.checkDestinations(ctx, target, deviceRules, geoRules, variants); err != nil {
+		return nil, err
+	}
+
+	url, err := s.repo.FindByManageToken(ctx, slug, hashManageToken(manageToken))
+	if err != nil {
+		return nil, err
+	}
+	if url == nil {
+		return nil, ErrInvalidManageToken
+	}
+
+	before := snapshot(url)
+	if req.TargetURL != nil {
+		url.TargetURL = *req.TargetURL
+	}
+	if req.UTM != nil {
+		url.UTM = req.UTM
+	}
+	if req.UTMDefault {
+		url.UTM = nil
+	}
+	if req.DeviceRules != nil {
+		url.DeviceRules = *req.DeviceRules
+	}
+	if req.GeoRules != nil {
+		url.GeoRules = geoRules
+	}
+	if req.Variants != nil {
+		url.Variants = *req.Variants
+	}
+	if req.StickyVariants != nil {
+		url.StickyVariants = *req.StickyVariants
+	}
+	if err := s.repo.Update(ctx, url); err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.Delete(ctx, slug); err != nil {
+		slog.Warn("failed to invalidate cache after update", "slug", slug, "error", err)
+	}
+	s.audit(ctx, url, model.AuditUpdate, model.ActorOwner, actorIP, before, snapshot(url))
+	return s.linkInfo(ctx, url), nil
+}
+
+// linkInfo builds the owner's view of url. Click and attempt counters are
+// best effort.
+func (s *URLService) linkInfo(ctx context.Context, url *model.URL) *LinkInfo {
+	info := &LinkInfo{
+		Slug:             url.Slug,
+		TargetURL:        url.TargetURL,
+		ExpiresAt:        url.ExpiresAt,
+		CreatedAt:        url.CreatedAt,
+		Protected:        url.PasswordHash != nil,
+		MaxClicks:        url.MaxClicks,
+		ClicksRemaining:  url.ClicksRemaining,
+		ActivatesAt:      url.ActivatesAt,
+		SlidingTTL:       url.SlidingTTL(),
+		RedirectStatus:   url.RedirectStatus,
+		QueryPassthrough: url.QueryPassthrough,
+		PrefixMatch:      url.PrefixMatch,
+		UTM:              url.UTM,
+		DeviceRules:      url.DeviceRules,
+		GeoRules:         url.GeoRules,
+		Variants:         url.Variants,
+		StickyVariants:   url.StickyVariants,
+		DisabledAt:       url.DisabledAt,
+	}
+	if url.DisabledReason != nil {
+		info.DisabledReason = *url.DisabledReason
+	}
+	var err error
+	if info.Clicks, info.VariantClicks, err = s.clickLog.Counts(ctx, url.ID); err != nil {
+		slog.Warn("failed to count clicks", "slug", url.Slug, "error", err)
+	}
+	if info.Protected {
+		if info.FailedAttempts, err = s.attempts.TotalFailures(ctx, url.Slug); err != nil {
+			slog.Warn("failed to read unlock attempts", "slug", url.Slug, "error", err)
+		}
+		if info.LockedFor, err = s.attempts.LockedFor(ctx, url.Slug); err != nil {
+			slog.Warn("failed to read unlock lockout", "slug", url.Slug, "error", err)
+		}
+	}
+	return info
+}
+
+func validDeviceRules(rules model.DeviceRules) bool {
+	if len(rules) > maxDeviceRules {
+		return false
+	}
+	for _, rule := range rules {
+		if !model.ValidPlatforms[rule.Platform] {
+			return false
+		}
+	}
+	return true
+}
+
+func validVariants(variants model.Variants) bool {
+	if len(variants) < 2 || len(variants) > maxVariants {
+		return false
+	}
+	seen := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if !variantNamePattern.MatchString(v.Name) || seen[v.Name] || v.Weight < 1 || v.Weight > maxVariantWeight {
+			return false
+		}
+		seen[v.Name] = true
+	}
+	return true
+}
+
+// normalizeGeoRules upper-cases country codes so they compare equal to the
+// GeoIP database's, and reports whether every rule is well formed.
+func normalizeGeoRules(rules model.GeoRules) (model.GeoRules, bool) {
+	if len(rules) > maxGeoRules {
+		return nil, false
+	}
+	out := make(model.GeoRules, len(rules))
+	for i, rule := range rules {
+		if len(rule.Countries) == 0 {
+			return nil, false
+		}
+		countries := make([]string, len(rule.Countries))
+		for j, code := range rule.Countries {
+			code = strings.ToUpper(code)
+			if !countryPattern.MatchString(code) {
+				return nil, false
+			}
+			countries[j] = code
+		}
+		out[i] = model.GeoRule{Countries: countries, TargetURL: rule.TargetURL}
+	}
+	return out, true
+}
+
+func validUTM(utm *model.UTM) bool {
+	if utm == nil {
+		return true
+	}
+	for _, value := range utm.Params() {
+		if len(value) > maxUTMValueLength {
+			return false
+		}
+	}
+	return true
+}
 
-	updated, err := s.repo.ExpireBySlug(ctx, slug, hash)
+func (s *URLService) ExpireEarly(ctx context.Context, slug, manageToken, actorIP string) error {
+	url, err := s.repo.FindByManageToken(ctx, slug, hashManageToken(manageToken))
+	if err != nil {
+		return err
+	}
+	if url == nil {
+		return ErrInvalidManageToken
+	}
+	updated, err := s.repo.ExpireBySlug(ctx, slug, url.ManageTokenHash)
 	if err != nil {
 		return err
 	}
@@ -183,9 +1081,85 @@ func (s *URLService) ExpireEarly(ctx context.Context, slug, manageToken string)
 		return ErrInvalidManageToken
 	}
 
+	s.forget(ctx, slug)
+	before := snapshot(url)
+	now := time.Now()
+	url.ExpiresAt = &now
+	s.audit(ctx, url, model.AuditExpire, model.ActorOwner, actorIP, before, snapshot(url))
+	return nil
+}
+
+// ChangePassword sets a new password on a link, or removes its protection
+// when password is empty. Remember-unlock tokens issued for the old password
+// stop validating.
+func (s *URLService) ChangePassword(ctx context.Context, slug, manageToken, password, actorIP string) (*LinkInfo, error) {
+	url, err := s.repo.FindByManageToken(ctx, slug, hashManageToken(manageToken))
+	if err != nil {
+		return nil, err
+	}
+	if url == nil {
+		return nil, ErrInvalidManageToken
+	}
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetPassword(ctx, url.ID, hash); err != nil {
+		return nil, err
+	}
+
 	if err := s.cache.Delete(ctx, slug); err != nil {
-		slog.Warn("failed to invalidate cache after early expire", "slug", slug, "error", err)
+		slog.Warn("failed to invalidate cache after password change", "slug", slug, "error", err)
+	}
+	before := snapshot(url)
+	url.PasswordHash = hash
+	s.audit(ctx, url, model.AuditPassword, model.ActorOwner, actorIP, before, snapshot(url))
+	return s.linkInfo(ctx, url), nil
+}
+
+// RotateManageToken replaces a link's manage token and returns the new one.
+// The old token stops working at once.
+func (s *URLService) RotateManageToken(ctx context.Context, slug, manageToken, actorIP string) (string, error) {
+	url, err := s.repo.FindByManageToken(ctx, slug, hashManageToken(manageToken))
+	if err != nil {
+		return "", err
+	}
+	if url == nil {
+		return "", ErrInvalidManageToken
+	}
+	token, tokenHash, err := generateManageToken()
+	if err != nil {
+		return "", fmt.Errorf("generating manage token: %w", err)
 	}
+	rotated, err := s.repo.RotateManageToken(ctx, slug, url.ManageTokenHash, tokenHash)
+	if err != nil {
+		return "", err
+	}
+	if !rotated {
+		return "", ErrInvalidManageToken
+	}
+	s.audit(ctx, url, model.AuditRotateToken, model.ActorOwner, actorIP, nil, nil)
+	return token, nil
+}
+
+// Report records a public abuse report against a link. Disabled links can
+// still be reported; expired ones cannot.
+func (s *URLService) Report(ctx context.Context, slug string, reason model.ReportReason, details, reporterIP string) error {
+	if !model.ValidReportReasons[reason] || len(details) > maxReportDetails {
+		return ErrInvalidReport
+	}
+	report := &model.AbuseReport{Slug: slug, Reason: reason, ReporterID: s.clientID(reporterIP)}
+	if details != "" {
+		report.Details = &details
+	}
+	created, err := s.reports.Create(ctx, report)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return ErrLinkNotFound
+	}
+	slog.Info("abuse report received", "slug", slug, "reason", reason)
 	return nil
 }
 
@@ -194,7 +1168,7 @@ func (s *URLService) CheckSlug(ctx context.Context, slug string) (available bool
 		return false, "", ErrInvalidSlugFormat
 	}
 
-	exists, err := s.repo.SlugExists(ctx, slug)
+	exists, err := s.slugTaken(ctx, slug)
 	if err != nil {
 		return false, "", err
 	}
@@ -235,7 +1209,7 @@ func (s *URLService) resolveSlug(ctx context.Context, requested string) (string,
 		return "", ErrInvalidSlugFormat
 	}
 
-	exists, err := s.repo.SlugExists(ctx, requested)
+	exists, err := s.slugTaken(ctx, requested)
 	if err != nil {
 		return "", err
 	}
@@ -253,6 +1227,14 @@ func (s *URLService) resolveSlug(ctx context.Context, requested string) (string,
 	return "", ErrSlugTaken
 }
 
+// slugTaken reports whether slug is reserved or held by a link.
+func (s *URLService) slugTaken(ctx context.Context, slug string) (bool, error) {
+	if reservedSlugs[strings.ToLower(slug)] {
+		return true, nil
+	}
+	return s.repo.SlugExists(ctx, slug)
+}
+
 func (s *URLService) generateUniqueSlug(ctx context.Context) (string, error) {
 	for range maxAutoSlugTries {
 		slug, err := randomBase62(autoSlugLength)
@@ -315,6 +1297,10 @@ func generateManageToken() (string, string, error) {
 	if err != nil {
 		return "", "", err
 	}
-	sum := sha256.Sum256([]byte(plain))
-	return plain, hex.EncodeToString(sum[:]), nil
+	return plain, hashManageToken(plain), nil
+}
+
+func hashManageToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
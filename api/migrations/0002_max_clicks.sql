@@ -0,0 +1,4 @@
+-- NULL means the link accepts unlimited visits.
+ALTER TABLE urls
+  ADD COLUMN max_clicks       INT UNSIGNED NULL AFTER manage_token_hash,
+  ADD COLUMN clicks_remaining INT UNSIGNED NULL AFTER max_clicks;
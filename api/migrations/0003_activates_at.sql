@@ -0,0 +1,3 @@
+-- NULL means the link is active as soon as it is created.
+ALTER TABLE urls
+  ADD COLUMN activates_at TIMESTAMP NULL AFTER clicks_remaining;
@@ -0,0 +1,3 @@
+-- NULL means the link never expires (only when the operator allows it).
+ALTER TABLE urls
+  MODIFY COLUMN expires_at TIMESTAMP NULL;
@@ -0,0 +1,4 @@
+-- When set, every visit pushes expires_at to at least NOW() plus this many
+-- seconds. NULL means the expiration is fixed.
+ALTER TABLE urls
+  ADD COLUMN sliding_ttl_seconds INT UNSIGNED NULL AFTER activates_at;
@@ -0,0 +1,4 @@
+-- HTTP status a link redirects with. NULL follows the server default, so
+-- changing DEFAULT_REDIRECT_STATUS also applies to existing links.
+ALTER TABLE urls
+  ADD COLUMN redirect_status SMALLINT UNSIGNED NULL AFTER sliding_ttl_seconds;
@@ -0,0 +1,6 @@
+-- query_passthrough forwards a visit's query string to the target; its value
+-- names the side that wins when both carry the same parameter. prefix_match
+-- lets /:slug/<rest> append <rest> to the target path.
+ALTER TABLE urls
+  ADD COLUMN query_passthrough ENUM('target', 'request') NULL AFTER redirect_status,
+  ADD COLUMN prefix_match TINYINT(1) NOT NULL DEFAULT 0 AFTER query_passthrough;
@@ -0,0 +1,4 @@
+-- Campaign parameters merged into the target at redirect time. NULL follows
+-- the server's DEFAULT_UTM_* settings.
+ALTER TABLE urls
+  ADD COLUMN utm JSON NULL AFTER prefix_match;
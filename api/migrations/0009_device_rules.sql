@@ -0,0 +1,4 @@
+-- Ordered per-platform targets, e.g. [{"platform":"ios","target_url":"..."}].
+-- NULL sends every visitor to target_url.
+ALTER TABLE urls
+  ADD COLUMN device_rules JSON NULL AFTER utm;
@@ -0,0 +1,4 @@
+-- Ordered per-country targets, e.g. [{"countries":["BR","PT"],"target_url":"..."}].
+-- NULL sends every visitor to target_url.
+ALTER TABLE urls
+  ADD COLUMN geo_rules JSON NULL AFTER device_rules;
@@ -0,0 +1,15 @@
+-- Weighted A/B destinations, e.g. [{"name":"a","target_url":"...","weight":70}].
+-- NULL sends every visitor to target_url.
+ALTER TABLE urls
+  ADD COLUMN variants JSON NULL AFTER geo_rules,
+  ADD COLUMN sticky_variants TINYINT(1) NOT NULL DEFAULT 0 AFTER variants;
+
+-- One row per revealed target. variant is NULL for links without variants.
+CREATE TABLE IF NOT EXISTS link_clicks (
+  id         BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  url_id     BIGINT UNSIGNED NOT NULL,
+  variant    VARCHAR(32)     NULL,
+  clicked_at TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_url_variant (url_id, variant),
+  CONSTRAINT fk_link_clicks_url FOREIGN KEY (url_id) REFERENCES urls (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,10 @@
+-- Operator-managed destination rules. pattern is an exact host
+-- ("example.com"), a wildcard covering every subdomain ("*.example.com"), or
+-- a CIDR matched against IP-literal hosts ("203.0.113.0/24").
+CREATE TABLE IF NOT EXISTS domain_rules (
+  id         BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  pattern    VARCHAR(255)           NOT NULL,
+  list       ENUM('block', 'allow') NOT NULL,
+  created_at TIMESTAMP              NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uq_pattern_list (pattern, list)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
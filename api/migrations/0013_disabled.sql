@@ -0,0 +1,5 @@
+-- Links taken down by the operator or a threat-list scan stop redirecting
+-- but keep their row. NULL disabled_at means the link is enabled.
+ALTER TABLE urls
+  ADD COLUMN disabled_at TIMESTAMP NULL AFTER sticky_variants,
+  ADD COLUMN disabled_reason VARCHAR(255) NULL AFTER disabled_at;
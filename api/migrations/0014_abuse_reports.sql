@@ -0,0 +1,13 @@
+-- Public reports of abusive links, reviewed by operators. Reports go with
+-- the link when it is deleted. Reporters are identified by a keyed hash of
+-- their IP ("ip:" and 32 hex characters), never the address itself.
+CREATE TABLE IF NOT EXISTS abuse_reports (
+  id          BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  url_id      BIGINT UNSIGNED NOT NULL,
+  reason      ENUM('phishing', 'malware', 'spam', 'illegal', 'other') NOT NULL,
+  details     VARCHAR(1000)   NULL,
+  reporter_id VARCHAR(64)     NOT NULL,
+  created_at  TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_url_id (url_id),
+  CONSTRAINT fk_abuse_reports_url FOREIGN KEY (url_id) REFERENCES urls (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,20 @@
+-- A keyed hash of the client IP that created each link, shown to operators.
+-- NULL for links created before it was recorded.
+ALTER TABLE urls
+  ADD COLUMN creator_id VARCHAR(64) NULL AFTER disabled_reason;
+
+-- Append-only record of actions taken on links. There is deliberately no
+-- foreign key: entries outlive the links they describe.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id           BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  url_id       BIGINT UNSIGNED NULL,
+  slug         VARCHAR(100)    NOT NULL,
+  action       VARCHAR(32)     NOT NULL,
+  actor        ENUM('owner', 'admin', 'system') NOT NULL,
+  actor_id     VARCHAR(64)     NULL,
+  state_before JSON            NULL,
+  state_after  JSON            NULL,
+  created_at   TIMESTAMP       NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_url_id (url_id, id),
+  INDEX idx_slug (slug, id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,4 @@
+-- Argon2id PHC strings are longer than the 60 characters of bcrypt hashes,
+-- which stay valid until they are upgraded on the next successful unlock.
+ALTER TABLE urls
+  MODIFY COLUMN password_hash VARCHAR(255) NULL;
@@ -1,8 +1,63 @@
 package migrations
 
-import _ "embed"
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
 
-// BootstrapSQL contains the idempotent schema bootstrap.
+// files holds the numbered schema migrations, applied in file name order.
+// 0001_bootstrap.sql is the original idempotent bootstrap, so existing
+// databases created before versioning simply record it as applied.
 //
-//go:embed bootstrap.sql
-var BootstrapSQL string
+//go:embed *.sql
+var files embed.FS
+
+// Migration is one schema change. Statements are executed one by one because
+// the MySQL driver does not accept multi-statement queries by default.
+type Migration struct {
+	Version    string
+	Statements []string
+}
+
+// All returns every embedded migration in the order it must be applied.
+func All() ([]Migration, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("listing migrations: %w", err)
+	}
+	sort.Strings(names)
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", name, err)
+		}
+		migrations = append(migrations, Migration{
+			Version:    strings.TrimSuffix(name, ".sql"),
+			Statements: splitStatements(string(data)),
+		})
+	}
+	return migrations, nil
+}
+
+// splitStatements splits a migration on semicolons, dropping comment-only
+// and blank fragments. Migrations must not contain semicolons in literals.
+func splitStatements(sql string) []string {
+	var stmts []string
+	for _, part := range strings.Split(sql, ";") {
+		var lines []string
+		for _, line := range strings.Split(part, "\n") {
+			if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			stmts = append(stmts, strings.TrimSpace(strings.Join(lines, "\n")))
+		}
+	}
+	return stmts
+}
@@ -0,0 +1,18 @@
+package pages
+
+import (
+	"embed"
+	"html/template"
+)
+
+// templateFS holds the server-rendered pages used when the API serves
+// visitors directly instead of handing them to the frontend.
+//
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Templates parses every embedded page. Pages are addressed by file name,
+// e.g. "gate.html", and share the partials defined in layout.html.
+func Templates() *template.Template {
+	return template.Must(template.New("").ParseFS(templateFS, "templates/*.html"))
+}
@@ -0,0 +1,13 @@
+//go:build embedweb
+
+package webui
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var distFS embed.FS
+
+var dist fs.FS = distFS
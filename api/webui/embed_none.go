@@ -0,0 +1,7 @@
+//go:build !embedweb
+
+package webui
+
+import "io/fs"
+
+var dist fs.FS
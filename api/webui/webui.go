@@ -0,0 +1,26 @@
+// Package webui exposes the built React frontend (web/dist) when the binary
+// is compiled with the embedweb build tag. Copy the Vite build output into
+// webui/dist before building:
+//
+//	cd web && VITE_BASE_PATH=/ VITE_API_URL= npm run build
+//	cp -r web/dist/. api/webui/dist/
+//	cd api && go build -tags embedweb ./cmd/server
+package webui
+
+import "io/fs"
+
+// FS returns the embedded frontend rooted at its index.html, or false when
+// the binary was built without it.
+func FS() (fs.FS, bool) {
+	if dist == nil {
+		return nil, false
+	}
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, false
+	}
+	if _, err := fs.Stat(sub, "index.html"); err != nil {
+		return nil, false
+	}
+	return sub, true
+}
@@ -38,7 +38,7 @@ function Footer() {
 
 export default function App() {
   return (
-    <BrowserRouter basename="/encurtador">
+    <BrowserRouter basename={import.meta.env.BASE_URL.replace(/\/$/, '')}>
       <div className="flex min-h-screen flex-col">
         <Header />
         <main className="flex-1">
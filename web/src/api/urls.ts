@@ -1,17 +1,64 @@
 const BASE = `${import.meta.env.VITE_API_URL}/api/v1`
 
+export interface UTM {
+  utm_source?: string
+  utm_medium?: string
+  utm_campaign?: string
+  utm_term?: string
+  utm_content?: string
+}
+
+export interface DeviceRule {
+  platform: 'ios' | 'android' | 'windows' | 'macos' | 'linux' | 'mobile' | 'desktop'
+  target_url: string
+}
+
+export interface GeoRule {
+  countries: string[]
+  target_url: string
+}
+
+export interface Variant {
+  name: string
+  target_url: string
+  weight: number
+}
+
 export interface CreateURLRequest {
   target_url: string
   slug?: string
-  ttl: string
+  ttl?: string
+  expires_at?: string
   password?: string
+  max_clicks?: number
+  activates_at?: string
+  sliding?: boolean
+  redirect_status?: 301 | 302 | 307 | 308
+  forward_query?: 'target' | 'request'
+  prefix?: boolean
+  utm?: UTM
+  device_rules?: DeviceRule[]
+  geo_rules?: GeoRule[]
+  variants?: Variant[]
+  sticky_variants?: boolean
 }
 
 export interface CreateURLResponse {
   slug: string
   short_url: string
-  expires_at: string
+  expires_at: string | null
   protected: boolean
+  max_clicks?: number
+  activates_at?: string
+  sliding_ttl?: number
+  redirect_status?: number
+  forward_query?: 'target' | 'request'
+  prefix?: boolean
+  utm?: UTM
+  device_rules?: DeviceRule[]
+  geo_rules?: GeoRule[]
+  variants?: Variant[]
+  sticky_variants?: boolean
   manage_token: string
 }
 
@@ -20,10 +67,23 @@ export interface CheckSlugResponse {
   suggestion?: string
 }
 
+// APIError carries the machine-readable code some error responses include
+// alongside the message.
+export class APIError extends Error {
+  code?: string
+
+  constructor(message: string, code?: string) {
+    super(message)
+    this.name = 'APIError'
+    this.code = code
+  }
+}
+
 async function handleResponse<T>(res: Response): Promise<T> {
   if (!res.ok) {
     const body = await res.json().catch(() => ({ error: res.statusText }))
-    throw new Error((body as { error?: string }).error ?? res.statusText)
+    const { error, code } = body as { error?: string; code?: string }
+    throw new APIError(error ?? res.statusText, code)
   }
   return res.json() as Promise<T>
 }
@@ -42,13 +102,29 @@ export async function checkSlug(slug: string): Promise<CheckSlugResponse> {
   return handleResponse<CheckSlugResponse>(res)
 }
 
-export async function unlockURL(slug: string, password: string): Promise<{ target_url: string }> {
+export interface UnlockResponse {
+  target_url: string
+  unlock_token?: string
+  unlock_expires_at?: string
+}
+
+// GateVisit is the path below the short link and the visit's query string,
+// which the API hands the gate so prefix and passthrough links survive it.
+export interface GateVisit {
+  rest?: string
+  query?: string
+}
+
+export async function unlockURL(slug: string, password: string, visit: GateVisit = {}): Promise<UnlockResponse> {
+  // credentials: 'include' lets the API set its remember-unlock cookie so
+  // later visits to the short link skip this gate.
   const res = await fetch(`${BASE}/urls/${encodeURIComponent(slug)}/unlock`, {
     method: 'POST',
+    credentials: 'include',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ password }),
+    body: JSON.stringify({ password, ...visit }),
   })
-  return handleResponse<{ target_url: string }>(res)
+  return handleResponse<UnlockResponse>(res)
 }
 
 export async function expireURL(slug: string, manageToken: string): Promise<void> {
@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react'
 import { Helmet } from 'react-helmet-async'
-import { useParams } from 'react-router-dom'
+import { useParams, useSearchParams } from 'react-router-dom'
 import { unlockURL } from '../api/urls'
 import { translateError } from '../utils/errors'
 
@@ -8,17 +8,20 @@ type GateState = 'checking' | 'protected' | 'not_found'
 
 export default function PasswordGate() {
   const { slug }                    = useParams<{ slug: string }>()
+  const [searchParams]              = useSearchParams()
   const [gateState, setGateState]   = useState<GateState>('checking')
   const [password, setPassword]     = useState('')
   const [error, setError]           = useState('')
   const [loading, setLoading]       = useState(false)
+  const rest                        = searchParams.get('rest') ?? undefined
+  const query                       = searchParams.get('query') ?? undefined
 
   // Probe with an empty password on mount: if the link is not protected the
   // backend returns the target URL immediately and we can skip the form entirely.
   useEffect(() => {
     async function probe() {
       try {
-        const { target_url } = await unlockURL(slug!, '')
+        const { target_url } = await unlockURL(slug!, '', { rest, query })
         window.location.href = target_url
       } catch (err) {
         const message = err instanceof Error ? err.message : ''
@@ -30,14 +33,14 @@ export default function PasswordGate() {
       }
     }
     probe()
-  }, [slug])
+  }, [slug, rest, query])
 
   async function handleSubmit(e: React.FormEvent) {
     e.preventDefault()
     setError('')
     setLoading(true)
     try {
-      const { target_url } = await unlockURL(slug!, password)
+      const { target_url } = await unlockURL(slug!, password, { rest, query })
       window.location.href = target_url
     } catch (err) {
       setError(err instanceof Error ? translateError(err.message) : 'Algo deu errado.')
@@ -84,7 +84,9 @@ export default function ResultCard({ result, onCreateAnother }: Props) {
               🔒 Protegido por senha
             </span>
           )}
-          <span className="text-xs">Expira em {formatDate(result.expires_at)}</span>
+          <span className="text-xs">
+            {result.expires_at ? `Expira em ${formatDate(result.expires_at)}` : 'Não expira'}
+          </span>
         </div>
 
         {/* Management token — displayed once */}
@@ -1,6 +1,6 @@
 import { useState, useEffect, useRef, useCallback } from 'react'
 import { Helmet } from 'react-helmet-async'
-import { createURL, checkSlug, type CreateURLResponse } from '../api/urls'
+import { APIError, createURL, checkSlug, type CreateURLResponse } from '../api/urls'
 import ResultCard from './ResultCard'
 import { translateError } from '../utils/errors'
 
@@ -68,7 +68,11 @@ export default function ShortenerForm() {
       })
       setResult(res)
     } catch (err) {
-      setError(err instanceof Error ? translateError(err.message) : 'Algo deu errado.')
+      if (err instanceof APIError) {
+        setError(translateError(err.message, err.code))
+      } else {
+        setError(err instanceof Error ? translateError(err.message) : 'Algo deu errado.')
+      }
     } finally {
       setLoading(false)
     }
@@ -1,5 +1,9 @@
 const TRANSLATIONS: Record<string, string> = {
   'invalid password':              'Senha incorreta.',
+  'link is not active yet':        'Este link ainda não está disponível.',
+  'too many failed attempts':      'Muitas tentativas incorretas. Aguarde alguns minutos e tente novamente.',
+  'too many password checks in progress':
+    'Servidor ocupado. Tente novamente em instantes.',
   'URL not found or expired':      'Este link não existe ou já expirou.',
   'invalid manage token':          'Token de gerenciamento inválido.',
   'slug is taken and no alternative could be found':
@@ -13,7 +17,18 @@ const TRANSLATIONS: Record<string, string> = {
   'Too Many Requests':             'Muitas requisições. Aguarde um momento e tente novamente.',
 }
 
-export function translateError(message: string): string {
+// Refusals whose message names the offending field are translated by code.
+const CODE_TRANSLATIONS: Record<string, string> = {
+  domain_blocked:      'Este domínio de destino não é permitido.',
+  private_target:      'O destino não pode apontar para um endereço privado, local ou reservado.',
+  unresolvable_target: 'Não foi possível encontrar o domínio de destino.',
+  malicious_target:    'Este destino está listado como malicioso.',
+}
+
+export function translateError(message: string, code?: string): string {
+  if (code && CODE_TRANSLATIONS[code]) {
+    return CODE_TRANSLATIONS[code]
+  }
   if (message.startsWith('target_url:')) {
     return 'A URL de destino deve ser um endereço http ou https válido.'
   }
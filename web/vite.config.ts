@@ -1,67 +1,80 @@
-import { defineConfig } from 'vite'
+import { defineConfig, loadEnv } from 'vite'
 import react from '@vitejs/plugin-react'
 import { VitePWA } from 'vite-plugin-pwa'
 
-export default defineConfig({
-  base: '/encurtador/',
-  plugins: [
-    react(),
-    VitePWA({
-      registerType: 'autoUpdate',
-      manifestFilename: 'site.webmanifest',
-      includeAssets: ['favicon.svg', 'favicon.ico', 'apple-touch-icon.png'],
-      manifest: {
-        name: 'Encurtador — Jorge Hermes',
-        short_name: 'Encurtador',
-        description: 'Encurtador de URLs simples e rápido. Crie links curtos com proteção de senha, slugs personalizados e expiração configurável.',
-        theme_color: '#09090b',
-        background_color: '#09090b',
-        display: 'standalone',
-        start_url: '/encurtador/',
-        scope: '/encurtador/',
-        lang: 'pt-BR',
-        icons: [
-          {
-            src: 'favicon-96x96.png',
-            sizes: '96x96',
-            type: 'image/png',
-          },
-          {
-            src: 'favicon.svg',
-            sizes: 'any',
-            type: 'image/svg+xml',
-            purpose: 'any',
-          },
-          {
-            src: 'apple-touch-icon.png',
-            sizes: '180x180',
-            type: 'image/png',
-            purpose: 'any',
-          },
-          {
-            src: 'web-app-manifest-192x192.png',
-            sizes: '192x192',
-            type: 'image/png',
-            purpose: 'maskable',
-          },
-          {
-            src: 'web-app-manifest-512x512.png',
-            sizes: '512x512',
-            type: 'image/png',
-            purpose: 'maskable',
-          },
-        ],
-      },
-      workbox: {
-        globPatterns: ['**/*.{js,css,html,ico,png,svg}'],
-        navigateFallback: '/encurtador/index.html',
-        navigateFallbackDenylist: [/^\/api\//],
+export default defineConfig(({ mode }) => {
+  // GitHub Pages serves the app under /encurtador/. Builds embedded in the Go
+  // binary set VITE_BASE_PATH=/ so the SPA lives at the API's root instead.
+  const base = loadEnv(mode, '.').VITE_BASE_PATH || '/encurtador/'
+
+  return {
+    base,
+    plugins: [
+      react(),
+      VitePWA({
+        registerType: 'autoUpdate',
+        manifestFilename: 'site.webmanifest',
+        includeAssets: ['favicon.svg', 'favicon.ico', 'apple-touch-icon.png'],
+        manifest: {
+          name: 'Encurtador — Jorge Hermes',
+          short_name: 'Encurtador',
+          description: 'Encurtador de URLs simples e rápido. Crie links curtos com proteção de senha, slugs personalizados e expiração configurável.',
+          theme_color: '#09090b',
+          background_color: '#09090b',
+          display: 'standalone',
+          start_url: base,
+          scope: base,
+          lang: 'pt-BR',
+          icons: [
+            {
+              src: 'favicon-96x96.png',
+              sizes: '96x96',
+              type: 'image/png',
+            },
+            {
+              src: 'favicon.svg',
+              sizes: 'any',
+              type: 'image/svg+xml',
+              purpose: 'any',
+            },
+            {
+              src: 'apple-touch-icon.png',
+              sizes: '180x180',
+              type: 'image/png',
+              purpose: 'any',
+            },
+            {
+              src: 'web-app-manifest-192x192.png',
+              sizes: '192x192',
+              type: 'image/png',
+              purpose: 'maskable',
+            },
+            {
+              src: 'web-app-manifest-512x512.png',
+              sizes: '512x512',
+              type: 'image/png',
+              purpose: 'maskable',
+            },
+          ],
+        },
+        workbox: {
+          globPatterns: ['**/*.{js,css,html,ico,png,svg}'],
+          navigateFallback: `${base}index.html`,
+          // Only the SPA's own routes may fall back to index.html: when the app
+          // shares the API's origin, every other path is a short link.
+          navigateFallbackAllowlist: [
+            new RegExp(`^${base}$`),
+            new RegExp(`^${base}(gate|manage)/`),
+            new RegExp(`^${base}404$`),
+          ],
+          navigateFallbackDenylist: [/^\/api\//],
+        },
+      }),
+    ],
+    server: {
+      proxy: {
+        '/api': 'http://localhost:8080',
       },
-    }),
-  ],
-  server: {
-    proxy: {
-      '/api': 'http://localhost:8080',
     },
-  },
+  }
 })